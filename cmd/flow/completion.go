@@ -0,0 +1,74 @@
+// Shell completion for the Flow CLI.
+//
+// `flow completion <shell>` prints a completion script generated by cobra to
+// stdout. Flags with a fixed set of values (--driver, --dialect) register
+// value completion so shells can offer them too.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gen "github.com/dministrator/flow/internal/generator"
+)
+
+// knownDrivers lists the database/sql driver names offered when completing
+// --driver. Any registered driver name still works.
+var knownDrivers = []string{"sqlite", "sqlite3", "postgres", "pgx", "mysql"}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script for flow and write it to stdout.
+
+Examples:
+  source <(flow completion bash)
+  flow completion zsh > "${fpath[1]}/_flow"
+  flow completion fish > ~/.config/fish/completions/flow.fish
+  flow completion powershell | Out-String | Invoke-Expression`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		switch args[0] {
+		case "bash":
+			return cmd.Root().GenBashCompletionV2(out, true)
+		case "zsh":
+			return cmd.Root().GenZshCompletion(out)
+		case "fish":
+			return cmd.Root().GenFishCompletion(out, true)
+		case "powershell":
+			return cmd.Root().GenPowerShellCompletionWithDesc(out)
+		}
+		return fmt.Errorf("unsupported shell %q", args[0])
+	},
+}
+
+// fixedValues returns a completion func offering values without falling
+// back to file names.
+func fixedValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// registerFlagCompletions wires value completion for flags with a fixed set
+// of values. It runs from main, after every init has defined its flags.
+func registerFlagCompletions() {
+	complete := func(cmd *cobra.Command, flag string, values ...string) {
+		if err := cmd.RegisterFlagCompletionFunc(flag, fixedValues(values...)); err != nil {
+			fmt.Fprintf(os.Stderr, "completion: %v\n", err)
+		}
+	}
+	complete(dbCmd, "driver", knownDrivers...)
+	dialects := []string{gen.DialectSQLite, gen.DialectPostgres, gen.DialectMySQL}
+	complete(genModelCmd, "dialect", dialects...)
+	complete(genScaffoldCmd, "dialect", dialects...)
+}
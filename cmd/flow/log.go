@@ -0,0 +1,79 @@
+// CLI output with verbosity control.
+//
+// Commands print through cliLog instead of bare fmt.Println so the
+// persistent --quiet and --verbose flags decide how much is shown. Errors
+// are returned from RunE and printed by main regardless of the level.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// logLevel controls how much the CLI prints.
+type logLevel int
+
+const (
+	levelQuiet logLevel = iota
+	levelNormal
+	levelVerbose
+)
+
+// cliLogger writes leveled messages to out.
+type cliLogger struct {
+	out   io.Writer
+	level logLevel
+}
+
+var (
+	flagVerbose bool
+	flagQuiet   bool
+
+	cliLog = &cliLogger{out: os.Stdout, level: levelNormal}
+)
+
+// setLevel derives the level from the --quiet/--verbose flags.
+func (l *cliLogger) setLevel(quiet, verbose bool) error {
+	switch {
+	case quiet && verbose:
+		return fmt.Errorf("--quiet and --verbose are mutually exclusive")
+	case quiet:
+		l.level = levelQuiet
+	case verbose:
+		l.level = levelVerbose
+	default:
+		l.level = levelNormal
+	}
+	return nil
+}
+
+// Infof prints a regular progress message; --quiet suppresses it.
+func (l *cliLogger) Infof(format string, args ...interface{}) {
+	if l.level >= levelNormal {
+		fmt.Fprintf(l.out, format+"\n", args...)
+	}
+}
+
+// Debugf prints extra detail shown only with --verbose.
+func (l *cliLogger) Debugf(format string, args ...interface{}) {
+	if l.level >= levelVerbose {
+		fmt.Fprintf(l.out, format+"\n", args...)
+	}
+}
+
+// Created reports a generated file. With --verbose it adds a short summary
+// of the file's content.
+func (l *cliLogger) Created(path string) {
+	l.Infof("created %s", path)
+	if l.level < levelVerbose {
+		return
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		l.Debugf("  (unreadable: %v)", err)
+		return
+	}
+	l.Debugf("  %d lines, %d bytes", bytes.Count(b, []byte("\n")), len(b))
+}
@@ -8,25 +8,33 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"database/sql"
-	routerpkg "github.com/dministrator/flow/internal/router"
 	flowpkg "github.com/dministrator/flow/pkg/flow"
 	"net/http"
 
 	gen "github.com/dministrator/flow/internal/generator"
 	mig "github.com/dministrator/flow/internal/migrations"
+	orm "github.com/dministrator/flow/internal/orm"
+
+	// database/sql driver for the db commands (--driver sqlite)
+	_ "modernc.org/sqlite"
 )
 
 const version = "0.1.0"
 
 func main() {
+	registerFlagCompletions()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -37,6 +45,9 @@ var rootCmd = &cobra.Command{
 	Use:   "flow",
 	Short: "Flow — an opinionated Go MVC web framework (CLI)",
 	Long:  "Flow CLI: run, generate and manage Flow web applications.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cliLog.setLevel(flagQuiet, flagVerbose)
+	},
 }
 
 func init() {
@@ -44,6 +55,8 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(dbCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "print extra detail (eg. generated file summaries)")
+	rootCmd.PersistentFlags().BoolVarP(&flagQuiet, "quiet", "q", false, "print only errors")
 }
 
 var serveAddr string
@@ -72,24 +85,7 @@ var serveCmd = &cobra.Command{
 		}
 
 		// Normal in-process serve (or --no-watch child)
-		// Use the default middleware stack in demo CLI server to provide
-		// sensible defaults (recovery, request-id, logging, metrics).
-		app := flowpkg.New("flow", flowpkg.WithAddr(serveAddr), flowpkg.WithDefaultMiddleware())
-
-		// small demo router: exposes a health endpoint and root index
-		r := routerpkg.New()
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-			w.WriteHeader(200)
-			_, _ = w.Write([]byte("Flow app running"))
-		})
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json; charset=utf-8")
-			w.WriteHeader(200)
-			_, _ = w.Write([]byte("{\"status\":\"ok\"}"))
-		})
-
-		app.SetRouter(r)
+		app := newServeApp(serveAddr)
 
 		// start and block until signal
 		if err := app.Start(); err != nil {
@@ -115,6 +111,25 @@ func init() {
 	serveCmd.Flags().StringSlice("watch-ext", []string{".go", ".tmpl", ".html", ".sql"}, "file extensions to trigger restarts (e.g. .go,.tmpl). Empty => watch all files")
 }
 
+// newServeApp builds the demo App run by `flow serve`. It uses the default
+// middleware stack (recovery, request-id, logging, metrics) and a small
+// router with a root index and a health endpoint; unknown paths get the
+// App's JSON 404 (override with flow.WithNotFoundHandler).
+func newServeApp(addr string) *flowpkg.App {
+	app := flowpkg.New("flow", flowpkg.WithAddr(addr), flowpkg.WithDefaultMiddleware())
+	app.SetRouterFunc(func(r *flowpkg.Router) {
+		r.Get("/", func(ctx *flowpkg.Context) {
+			ctx.SetHeader("Content-Type", "text/plain; charset=utf-8")
+			ctx.Status(http.StatusOK)
+			_, _ = ctx.W.Write([]byte("Flow app running"))
+		})
+		r.Get("/health", func(ctx *flowpkg.Context) {
+			_ = ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+		})
+	})
+	return app
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the CLI version",
@@ -125,163 +140,191 @@ var versionCmd = &cobra.Command{
 
 var dbCmd = &cobra.Command{
 	Use:   "db",
-	Short: "Database tasks (migrate, rollback)",
+	Short: "Database tasks (migrate, rollback, status, new)",
 }
 
 var dbDir string
 var dbDriver string
 var dbDSN string
+var dbTable string
+var dbSchema string
+var dbEnv string
 
 var dbMigrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Apply all pending migrations in a directory",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if dbDriver == "" || dbDSN == "" {
-			return fmt.Errorf("driver and dsn flags are required to run migrations")
+	RunE: withRedactedDSN(func(cmd *cobra.Command, args []string) error {
+		if err := resolveDBConfig("run migrations"); err != nil {
+			return err
 		}
 		db, err := sql.Open(dbDriver, dbDSN)
 		if err != nil {
-			return err
+			return fmt.Errorf("open database %s: %w", orm.RedactDSN(dbDSN), err)
 		}
 		defer db.Close()
-		runner := &mig.MigrationRunner{}
-
-		// list applied before
-		appliedBefore, err := runner.AppliedMigrations(db)
-		if err != nil {
-			return err
-		}
+		runner := &mig.MigrationRunner{TableName: dbTable, Schema: dbSchema}
+		cliLog.Debugf("migrations dir %s, driver %s, dsn %s", dbDir, dbDriver, orm.RedactDSN(dbDSN))
 
 		pending, err := runner.PendingMigrations(dbDir, db)
 		if err != nil {
 			return err
 		}
 		if len(pending) == 0 {
-			fmt.Println("No pending migrations to apply.")
+			cliLog.Infof("No pending migrations to apply.")
 			return nil
 		}
-		fmt.Println("Pending migrations:")
+		cliLog.Infof("Pending migrations:")
 		for _, p := range pending {
-			fmt.Println(" -", p)
-		}
-
-		if err := runner.ApplyAll(dbDir, db); err != nil {
-			return err
+			cliLog.Infof(" - %s", p)
 		}
 
-		// list newly applied
-		appliedAfter, err := runner.AppliedMigrations(db)
-		if err != nil {
-			return err
+		// stream progress as each migration is applied
+		cliLog.Infof("Applied migrations:")
+		runner.OnApply = func(name string, dur time.Duration) {
+			cliLog.Infof(" - %s (%s)", name, dur.Round(time.Millisecond))
 		}
-		// compute diff appliedAfter - appliedBefore
-		beforeSet := make(map[string]struct{}, len(appliedBefore))
-		for _, b := range appliedBefore {
-			beforeSet[b] = struct{}{}
-		}
-		var newly []string
-		for _, a := range appliedAfter {
-			if _, ok := beforeSet[a]; !ok {
-				newly = append(newly, a)
-			}
-		}
-		if len(newly) == 0 {
-			fmt.Println("No new migrations were applied.")
-			return nil
-		}
-		fmt.Println("Applied migrations:")
-		for _, n := range newly {
-			fmt.Println(" -", n)
-		}
-		return nil
-	},
+		return runner.ApplyAll(dbDir, db)
+	}),
 }
 
 var dbRollbackCmd = &cobra.Command{
 	Use:   "rollback",
 	Short: "Rollback the most recent migration",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if dbDriver == "" || dbDSN == "" {
-			return fmt.Errorf("driver and dsn flags are required to rollback migrations")
+	RunE: withRedactedDSN(func(cmd *cobra.Command, args []string) error {
+		if err := resolveDBConfig("rollback migrations"); err != nil {
+			return err
 		}
 		db, err := sql.Open(dbDriver, dbDSN)
 		if err != nil {
-			return err
+			return fmt.Errorf("open database %s: %w", orm.RedactDSN(dbDSN), err)
 		}
 		defer db.Close()
-		runner := &mig.MigrationRunner{}
+		runner := &mig.MigrationRunner{TableName: dbTable, Schema: dbSchema}
+		cliLog.Debugf("migrations dir %s, driver %s, dsn %s", dbDir, dbDriver, orm.RedactDSN(dbDSN))
 
 		applied, err := runner.AppliedMigrations(db)
 		if err != nil {
 			return err
 		}
 		if len(applied) == 0 {
-			fmt.Println("No applied migrations found; nothing to rollback.")
+			cliLog.Infof("No applied migrations found; nothing to rollback.")
 			return nil
 		}
-		last := applied[len(applied)-1]
-		fmt.Println("Rolling back migration:", last)
-		if err := runner.RollbackLast(dbDir, db); err != nil {
-			return err
+		cliLog.Infof("Rolling back migration: %s", applied[len(applied)-1])
+		runner.OnRollback = func(name string) {
+			cliLog.Infof("Rolled back: %s", name)
 		}
-		fmt.Println("Rolled back:", last)
-		return nil
-	},
+		return runner.RollbackLast(dbDir, db)
+	}),
 }
 
 var dbStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show applied and pending migrations",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if dbDriver == "" || dbDSN == "" {
-			return fmt.Errorf("driver and dsn flags are required to check status")
+	RunE: withRedactedDSN(func(cmd *cobra.Command, args []string) error {
+		if err := resolveDBConfig("check status"); err != nil {
+			return err
 		}
 		db, err := sql.Open(dbDriver, dbDSN)
 		if err != nil {
-			return err
+			return fmt.Errorf("open database %s: %w", orm.RedactDSN(dbDSN), err)
 		}
 		defer db.Close()
-		runner := &mig.MigrationRunner{}
-		applied, err := runner.AppliedMigrations(db)
+		runner := &mig.MigrationRunner{TableName: dbTable, Schema: dbSchema}
+		cliLog.Debugf("migrations dir %s, driver %s, dsn %s", dbDir, dbDriver, orm.RedactDSN(dbDSN))
+		st, err := runner.Status(dbDir, db)
 		if err != nil {
 			return err
 		}
-		pending, err := runner.PendingMigrations(dbDir, db)
-		if err != nil {
-			return err
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(st)
 		}
-		fmt.Println("Applied migrations:")
-		if len(applied) == 0 {
-			fmt.Println(" (none)")
+		cliLog.Infof("Applied migrations:")
+		if len(st.Applied) == 0 {
+			cliLog.Infof(" (none)")
 		} else {
-			for _, a := range applied {
-				fmt.Println(" -", a)
+			for _, a := range st.Applied {
+				cliLog.Infof(" - %s", a.Name)
 			}
 		}
-		fmt.Println("Pending migrations:")
-		if len(pending) == 0 {
-			fmt.Println(" (none)")
+		cliLog.Infof("Pending migrations:")
+		if len(st.Pending) == 0 {
+			cliLog.Infof(" (none)")
 		} else {
-			for _, p := range pending {
-				fmt.Println(" -", p)
+			for _, p := range st.Pending {
+				cliLog.Infof(" - %s", p)
 			}
 		}
 		return nil
+	}),
+}
+
+var dbNewCmd = &cobra.Command{
+	Use:   "new [name]",
+	Short: "Create an empty timestamped migration pair in --dir",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		created, err := gen.GenerateMigration(dbDir, args[0])
+		if err != nil {
+			return err
+		}
+		for _, c := range created {
+			cliLog.Created(c)
+		}
+		return nil
 	},
 }
 
+// resolveDBConfig fills --driver and --dsn from config/database.yaml for
+// the selected environment (--env, else FLOW_ENV, else development) when
+// --dsn is not given. Explicit flags win over the file.
+func resolveDBConfig(action string) error {
+	if dbDSN == "" {
+		cfg, err := flowpkg.LoadDatabaseConfig(flowpkg.DefaultDatabaseConfigPath, dbEnv)
+		switch {
+		case err == nil:
+			dbDSN = cfg.DSN
+			if dbDriver == "" {
+				dbDriver = cfg.Driver
+			}
+			cliLog.Debugf("using %s", flowpkg.DefaultDatabaseConfigPath)
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+	if dbDriver == "" || dbDSN == "" {
+		return fmt.Errorf("driver and dsn flags are required to %s (or set them in %s)", action, flowpkg.DefaultDatabaseConfigPath)
+	}
+	return nil
+}
+
+// withRedactedDSN wraps a db command so returned errors never echo the raw
+// --dsn, which may contain a password.
+func withRedactedDSN(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return orm.RedactError(run(cmd, args), dbDSN)
+	}
+}
+
 func init() {
 	dbCmd.AddCommand(dbMigrateCmd)
 	dbCmd.AddCommand(dbRollbackCmd)
 	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbNewCmd)
 	dbCmd.PersistentFlags().StringVar(&dbDir, "dir", "db/migrate", "migrations directory")
 	dbCmd.PersistentFlags().StringVar(&dbDriver, "driver", "", "database driver (eg. postgres, mysql)")
 	dbCmd.PersistentFlags().StringVar(&dbDSN, "dsn", "", "database DSN")
+	dbCmd.PersistentFlags().StringVar(&dbTable, "table", mig.DefaultTableName, "table used to track applied migrations")
+	dbStatusCmd.Flags().Bool("json", false, `print status as JSON: {"applied":[{"name","applied_at"}],"pending":[...]}`)
+	dbCmd.PersistentFlags().StringVar(&dbSchema, "schema", "", "schema qualifying the tracking table (postgres)")
+	dbCmd.PersistentFlags().StringVar(&dbEnv, "env", "", "environment in "+flowpkg.DefaultDatabaseConfigPath+" used when --dsn is not set (default $FLOW_ENV or development)")
 }
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
-	Short: "Code generators (controller, model, scaffold)",
+	Short: "Code generators (controller, model, scaffold, policy, task, config)",
 }
 
 var generateTarget string
@@ -307,7 +350,7 @@ var genControllerCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		fmt.Println("created", dst)
+		cliLog.Created(dst)
 		return nil
 	},
 }
@@ -331,13 +374,13 @@ var genModelCmd = &cobra.Command{
 			}
 		}
 		force, _ := cmd.Flags().GetBool("force")
-		// model generation currently supports --force to overwrite
-		opts := gen.GenOptions{Force: force}
+		dialect, _ := cmd.Flags().GetString("dialect")
+		opts := gen.GenOptions{Force: force, Dialect: dialect}
 		dst, err := gen.GenerateModelWithOptions(root, name, opts, fields...)
 		if err != nil {
 			return err
 		}
-		fmt.Println("created", dst)
+		cliLog.Created(dst)
 		return nil
 	},
 }
@@ -363,13 +406,89 @@ var genScaffoldCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 		skipMigs, _ := cmd.Flags().GetBool("skip-migrations")
 		noViews, _ := cmd.Flags().GetBool("no-views")
-		opts := gen.GenOptions{Force: force, SkipMigrations: skipMigs, NoViews: noViews}
+		dialect, _ := cmd.Flags().GetString("dialect")
+		uniques, _ := cmd.Flags().GetStringArray("unique")
+		api, _ := cmd.Flags().GetBool("api")
+		opts := gen.GenOptions{Force: force, SkipMigrations: skipMigs, NoViews: noViews, Dialect: dialect, API: api}
+		for _, u := range uniques {
+			opts.UniqueConstraints = append(opts.UniqueConstraints, gen.ParseColumnList(u))
+		}
 		created, err := gen.GenerateScaffoldWithOptions(root, name, opts, fields...)
 		if err != nil {
 			return err
 		}
 		for _, c := range created {
-			fmt.Println("created", c)
+			cliLog.Created(c)
+		}
+		return nil
+	},
+}
+
+var genPolicyCmd = &cobra.Command{
+	Use:   "policy [name]",
+	Short: "Generate an authorization policy (app/policies)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := generateTarget
+		if root == "" {
+			var err error
+			root, err = os.Getwd()
+			if err != nil {
+				return err
+			}
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		dst, err := gen.GeneratePolicy(root, args[0], gen.GenOptions{Force: force})
+		if err != nil {
+			return err
+		}
+		cliLog.Created(dst)
+		return nil
+	},
+}
+
+var genTaskCmd = &cobra.Command{
+	Use:   "task [name]",
+	Short: "Generate an app task (app/tasks) and the cmd/tasks runner",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := generateTarget
+		if root == "" {
+			var err error
+			root, err = os.Getwd()
+			if err != nil {
+				return err
+			}
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		created, err := gen.GenerateTask(root, args[0], gen.GenOptions{Force: force})
+		for _, c := range created {
+			cliLog.Created(c)
+		}
+		return err
+	},
+}
+
+var genConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Generate an app/config package and .env.example",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := generateTarget
+		if root == "" {
+			var err error
+			root, err = os.Getwd()
+			if err != nil {
+				return err
+			}
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		created, err := gen.GenerateConfig(root, gen.GenOptions{Force: force})
+		if err != nil {
+			return err
+		}
+		for _, c := range created {
+			cliLog.Created(c)
 		}
 		return nil
 	},
@@ -379,10 +498,20 @@ func init() {
 	generateCmd.AddCommand(genControllerCmd)
 	generateCmd.AddCommand(genModelCmd)
 	generateCmd.AddCommand(genScaffoldCmd)
+	generateCmd.AddCommand(genConfigCmd)
+	generateCmd.AddCommand(genPolicyCmd)
+	generateCmd.AddCommand(genTaskCmd)
+	genConfigCmd.Flags().Bool("force", false, "overwrite existing files")
 	genControllerCmd.Flags().Bool("force", false, "overwrite existing files")
 	genModelCmd.Flags().Bool("force", false, "overwrite existing files")
 	genScaffoldCmd.Flags().Bool("force", false, "overwrite existing files")
+	genPolicyCmd.Flags().Bool("force", false, "overwrite existing files")
+	genTaskCmd.Flags().Bool("force", false, "overwrite an existing task file")
 	genScaffoldCmd.Flags().Bool("skip-migrations", false, "do not create migration files")
 	genScaffoldCmd.Flags().Bool("no-views", false, "do not generate view files")
+	genModelCmd.Flags().String("dialect", gen.DialectSQLite, "SQL dialect for column types (sqlite, postgres, mysql)")
+	genScaffoldCmd.Flags().String("dialect", gen.DialectSQLite, "SQL dialect for column types (sqlite, postgres, mysql)")
+	genScaffoldCmd.Flags().Bool("api", false, "generate a JSON API controller and no views")
+	genScaffoldCmd.Flags().StringArray("unique", nil, "composite unique constraint as comma-separated columns, eg. user_id,slug (repeatable)")
 	generateCmd.PersistentFlags().StringVar(&generateTarget, "target", "", "target project root (defaults to cwd)")
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeAppJSONNotFound(t *testing.T) {
+	h := newServeApp(":0").Handler()
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/nope", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected a JSON problem, got content type %q", ct)
+	}
+	var p struct {
+		Status int    `json:"status"`
+		Title  string `json:"title"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+		t.Fatalf("decode 404 body: %v", err)
+	}
+	if p.Status != http.StatusNotFound || p.Title != "Not Found" {
+		t.Fatalf("unexpected 404 body %+v", p)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected /health to be served, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,53 @@
+// App tasks for the Flow CLI.
+//
+// Tasks are registered by app code (flow.RegisterTask), so they cannot be
+// linked into this binary. `flow task` instead runs the project's own task
+// runner, cmd/tasks, which `flow generate task` creates, with `go run`.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// taskRunnerDir is the project's task runner package, relative to the
+// project root.
+const taskRunnerDir = "cmd/tasks"
+
+var taskCmd = &cobra.Command{
+	Use:   "task [name] [args...]",
+	Short: "Run an app task, or list the tasks when no name is given",
+	Long: `Run a task registered with flow.RegisterTask in the current project.
+
+The project's task runner (` + taskRunnerDir + `, created by "flow generate task")
+is started with "go run"; arguments after the name are passed to the task
+unparsed, flags included.
+
+Examples:
+  flow task
+  flow task recalculate_stats --since 2024-01-01`,
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
+			return cmd.Help()
+		}
+		if _, err := os.Stat(filepath.FromSlash(taskRunnerDir)); errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no task runner in %s; create one with \"flow generate task NAME\"", taskRunnerDir)
+		}
+		run := exec.CommandContext(cmd.Context(), "go", append([]string{"run", "./" + taskRunnerDir}, args...)...)
+		run.Stdin = os.Stdin
+		run.Stdout = cmd.OutOrStdout()
+		run.Stderr = cmd.ErrOrStderr()
+		return run.Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(taskCmd)
+}
@@ -14,13 +14,13 @@ func NewUsersController(app *flow.App) *UsersController {
 func (u *UsersController) Index(ctx *flow.Context) {
 	// simple demo data
 	data := map[string]interface{}{"Title": "Users", "Items": []string{"Alice", "Bob"}}
-	_ = u.Render(ctx, "users/index", data)
+	u.RenderOrError(ctx, "users/index", data)
 }
 
 func (u *UsersController) Show(ctx *flow.Context) {
 	id := ctx.Param("id")
 	data := map[string]interface{}{"Title": "User", "ID": id}
-	_ = u.Render(ctx, "users/show", data)
+	u.RenderOrError(ctx, "users/show", data)
 }
 
 func (u *UsersController) New(ctx *flow.Context) {
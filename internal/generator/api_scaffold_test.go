@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI_GenerateScaffoldAPI_CRUDLifecycle(t *testing.T) {
+	repo := findRepoRoot()
+	modName, err := readModuleName(repo)
+	if err != nil {
+		t.Fatalf("read module name: %v", err)
+	}
+
+	// the project must live inside the module so the generated packages build
+	projDir, err := os.MkdirTemp(filepath.Join(repo, "examples"), "gen-api-*")
+	if err != nil {
+		t.Fatalf("mktemp proj dir: %v", err)
+	}
+	defer os.RemoveAll(projDir)
+
+	bin := filepath.Join(t.TempDir(), "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	gen := exec.Command(bin, "generate", "scaffold", "post", "title:string", "--api", "--target", projDir)
+	gen.Dir = repo
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Fatalf("generate scaffold --api failed: %v\n%s", err, string(out))
+	}
+	if _, err := os.Stat(filepath.Join(projDir, "app", "views")); !os.IsNotExist(err) {
+		t.Fatalf("expected no views for --api scaffold, stat err: %v", err)
+	}
+
+	rel := strings.TrimPrefix(projDir, repo+string(os.PathSeparator))
+	ctrlImport := modName + "/" + filepath.ToSlash(filepath.Join(rel, "app", "controllers"))
+	mainSrc := `package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+
+    migrations "` + modName + `/internal/migrations"
+    orm "` + modName + `/internal/orm"
+    flow "` + modName + `/pkg/flow"
+    controllers "` + ctrlImport + `"
+    _ "modernc.org/sqlite"
+)
+
+func main() {
+    adapter, err := orm.Connect("file:api_scaffold?mode=memory&cache=shared")
+    if err != nil {
+        log.Fatalf("connect: %v", err)
+    }
+    defer adapter.Close()
+    if err := (&migrations.MigrationRunner{}).ApplyAll("db/migrate", adapter.SQLDB); err != nil {
+        log.Fatalf("migrate: %v", err)
+    }
+
+    app := flow.New("api-scaffold", flow.WithBun(adapter))
+    r := flow.NewRouter(app)
+    if err := controllers.RegisterPostControllerRoutes(r, app); err != nil {
+        log.Fatalf("routes: %v", err)
+    }
+    srv := httptest.NewServer(r)
+    defer srv.Close()
+
+    do := func(method, path, body string, want int) map[string]interface{} {
+        req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+        if err != nil {
+            log.Fatalf("%s %s: %v", method, path, err)
+        }
+        req.Header.Set("Content-Type", "application/json")
+        res, err := http.DefaultClient.Do(req)
+        if err != nil {
+            log.Fatalf("%s %s: %v", method, path, err)
+        }
+        defer res.Body.Close()
+        if res.StatusCode != want {
+            log.Fatalf("%s %s: expected %d, got %d", method, path, want, res.StatusCode)
+        }
+        out := map[string]interface{}{}
+        if res.StatusCode != http.StatusNoContent {
+            if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+                log.Fatalf("%s %s: decode: %v", method, path, err)
+            }
+        }
+        return out
+    }
+
+    created := do("POST", "/posts", ` + "`" + `{"title":"hello"}` + "`" + `, http.StatusCreated)
+    path := fmt.Sprintf("/posts/%d", int(created["id"].(float64)))
+    if got := do("GET", path, "", http.StatusOK); got["title"] != "hello" {
+        log.Fatalf("show: unexpected body %v", got)
+    }
+    if got := do("PATCH", path, ` + "`" + `{"title":"patched"}` + "`" + `, http.StatusOK); got["title"] != "patched" {
+        log.Fatalf("patch: unexpected body %v", got)
+    }
+    do("PATCH", path, ` + "`" + `{"created_at":"2000-01-01T00:00:00Z"}` + "`" + `, http.StatusOK)
+    if got := do("GET", path, "", http.StatusOK); strings.HasPrefix(fmt.Sprint(got["created_at"]), "2000") {
+        log.Fatalf("patch: created_at was written: %v", got)
+    }
+    if got := do("PUT", path, ` + "`" + `{"title":"replaced"}` + "`" + `, http.StatusOK); got["title"] != "replaced" {
+        log.Fatalf("put: unexpected body %v", got)
+    }
+    list := do("GET", "/posts?page=1&per_page=10", "", http.StatusOK)
+    if p := list["pagination"].(map[string]interface{}); p["total"].(float64) != 1 {
+        log.Fatalf("index: unexpected pagination %v", p)
+    }
+    do("DELETE", path, "", http.StatusNoContent)
+    do("GET", path, "", http.StatusNotFound)
+    fmt.Println("CRUD OK")
+}
+`
+	if err := os.WriteFile(filepath.Join(projDir, "main.go"), []byte(mainSrc), 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go")
+	cmd.Dir = projDir
+	out, err := cmd.CombinedOutput()
+	t.Logf("run output: %s", string(out))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if !strings.Contains(string(out), "CRUD OK") {
+		t.Fatalf("unexpected output: %s", string(out))
+	}
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -118,3 +119,270 @@ func TestCLI_GenerateModel_ForceOverwrite(t *testing.T) {
 		t.Fatalf("generated model missing bun tag for title after --force: %s", s)
 	}
 }
+
+func TestCLI_CompletionBash(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	// build CLI
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	out, err := exec.Command(bin, "completion", "bash").Output()
+	if err != nil {
+		t.Fatalf("cli completion bash failed: %v", err)
+	}
+	if len(out) == 0 || !strings.Contains(string(out), "flow") {
+		t.Fatalf("expected a bash completion script for flow, got %q", string(out))
+	}
+
+	// --driver offers known driver names
+	out, err = exec.Command(bin, "__complete", "db", "migrate", "--driver", "").Output()
+	if err != nil {
+		t.Fatalf("cli __complete failed: %v", err)
+	}
+	if !strings.Contains(string(out), "postgres") {
+		t.Fatalf("expected driver completions, got %q", string(out))
+	}
+}
+
+func TestCLI_GenerateModel_Quiet(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	// build CLI
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	cmd := exec.Command(bin, "--quiet", "generate", "model", "post", "title:string", "--target", tmp)
+	cmd.Dir = repo
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("cli generate model --quiet failed: %v\noutput: %s", err, string(out))
+	}
+	if strings.Contains(string(out), "created") {
+		t.Fatalf("expected --quiet to suppress created lines, got %q", string(out))
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "app", "models", "post.go")); err != nil {
+		t.Fatalf("expected model to be generated: %v", err)
+	}
+
+	// --verbose adds a summary for each created file
+	cmd = exec.Command(bin, "generate", "controller", "posts", "--target", tmp, "--verbose")
+	cmd.Dir = repo
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("cli generate controller --verbose failed: %v\noutput: %s", err, string(out))
+	}
+	if !strings.Contains(string(out), "created") || !strings.Contains(string(out), "bytes") {
+		t.Fatalf("expected verbose file summary, got %q", string(out))
+	}
+}
+
+func TestCLI_DBStatusJSON(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	// build CLI
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	migDir := filepath.Join(tmp, "migrate")
+	if err := os.MkdirAll(migDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, sql string) {
+		if err := os.WriteFile(filepath.Join(migDir, name), []byte(sql), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbArgs := []string{"--dir", migDir, "--driver", "sqlite", "--dsn", "file:" + filepath.Join(tmp, "app.db")}
+
+	// apply the first migration, then add a second that stays pending
+	write("20260101000000_create_a.up.sql", "CREATE TABLE a (id INTEGER);")
+	if out, err := exec.Command(bin, append([]string{"db", "migrate"}, dbArgs...)...).CombinedOutput(); err != nil {
+		t.Fatalf("db migrate failed: %v\noutput: %s", err, string(out))
+	}
+	write("20260102000000_create_b.up.sql", "CREATE TABLE b (id INTEGER);")
+
+	out, err := exec.Command(bin, append([]string{"db", "status", "--json"}, dbArgs...)...).Output()
+	if err != nil {
+		t.Fatalf("db status --json failed: %v", err)
+	}
+	var st struct {
+		Applied []struct {
+			Name      string `json:"name"`
+			AppliedAt string `json:"applied_at"`
+		} `json:"applied"`
+		Pending []string `json:"pending"`
+	}
+	if err := json.Unmarshal(out, &st); err != nil {
+		t.Fatalf("decode status json: %v\noutput: %s", err, string(out))
+	}
+	if len(st.Applied) != 1 || st.Applied[0].Name != "20260101000000_create_a" || st.Applied[0].AppliedAt == "" {
+		t.Fatalf("unexpected applied: %+v", st.Applied)
+	}
+	if len(st.Pending) != 1 || st.Pending[0] != "20260102000000_create_b" {
+		t.Fatalf("unexpected pending: %v", st.Pending)
+	}
+}
+
+func TestCLI_DBNew(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	// build CLI
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	migDir := filepath.Join(tmp, "migrate")
+	dbArgs := []string{"--dir", migDir}
+	out, err := exec.Command(bin, append([]string{"db", "new", "add_users_index"}, dbArgs...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("db new failed: %v\noutput: %s", err, string(out))
+	}
+	ups, _ := filepath.Glob(filepath.Join(migDir, "*_add_users_index.up.sql"))
+	downs, _ := filepath.Glob(filepath.Join(migDir, "*_add_users_index.down.sql"))
+	if len(ups) != 1 || len(downs) != 1 {
+		t.Fatalf("expected one up and one down migration, got %v %v", ups, downs)
+	}
+	upTS, _, _ := strings.Cut(filepath.Base(ups[0]), "_")
+	downTS, _, _ := strings.Cut(filepath.Base(downs[0]), "_")
+	if upTS != downTS || len(upTS) != 14 {
+		t.Fatalf("expected matching 14-digit timestamps, got %s and %s", upTS, downTS)
+	}
+	if !strings.Contains(string(out), ups[0]) || !strings.Contains(string(out), downs[0]) {
+		t.Fatalf("expected created paths in output, got %q", string(out))
+	}
+
+	// a second migration in the same second still sorts after the first
+	if out, err := exec.Command(bin, append([]string{"db", "new", "backfill_users"}, dbArgs...)...).CombinedOutput(); err != nil {
+		t.Fatalf("second db new failed: %v\noutput: %s", err, string(out))
+	}
+	next, _ := filepath.Glob(filepath.Join(migDir, "*_backfill_users.up.sql"))
+	if len(next) != 1 || filepath.Base(next[0]) <= filepath.Base(ups[0]) {
+		t.Fatalf("expected backfill_users to sort after add_users_index, got %v", next)
+	}
+
+	// the empty migrations apply cleanly
+	dbArgs = append(dbArgs, "--driver", "sqlite", "--dsn", "file:"+filepath.Join(tmp, "app.db"))
+	if out, err := exec.Command(bin, append([]string{"db", "migrate"}, dbArgs...)...).CombinedOutput(); err != nil {
+		t.Fatalf("db migrate failed: %v\noutput: %s", err, string(out))
+	}
+
+	if out, err := exec.Command(bin, "db", "new", "bad-name", "--dir", migDir).CombinedOutput(); err == nil {
+		t.Fatalf("expected invalid name to fail, got %s", string(out))
+	}
+}
+
+func TestCLI_DBUsesDatabaseConfig(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	// build CLI
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	if err := os.MkdirAll(filepath.Join(tmp, "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "development:\n  driver: sqlite\n  dsn: file:dev.db\ntest:\n  driver: sqlite\n  dsn: file:test.db\n"
+	if err := os.WriteFile(filepath.Join(tmp, "config", "database.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	migDir := filepath.Join(tmp, "db", "migrate")
+	if err := os.MkdirAll(migDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(migDir, "20260101000000_create_a.up.sql"), []byte("CREATE TABLE a (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bin, "db", "migrate", "--env", "test")
+	cmd.Dir = tmp
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("db migrate --env test failed: %v\noutput: %s", err, string(out))
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "test.db")); err != nil {
+		t.Fatalf("expected test.db to be created: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "dev.db")); err == nil {
+		t.Fatalf("development database should not have been touched")
+	}
+}
+
+func TestCLI_Task(t *testing.T) {
+	repo := findRepoRoot()
+	tmp := t.TempDir()
+
+	bin := filepath.Join(tmp, "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	// without a runner flow task explains how to create one
+	noRunner := exec.Command(bin, "task")
+	noRunner.Dir = tmp
+	if out, err := noRunner.CombinedOutput(); err == nil || !strings.Contains(string(out), "flow generate task") {
+		t.Fatalf("expected a missing runner error, got %v: %s", err, string(out))
+	}
+
+	// the project lives inside the repo module so it can import pkg/flow
+	projDir, err := os.MkdirTemp(filepath.Join(repo, "examples"), "gen-task-*")
+	if err != nil {
+		t.Fatalf("mktemp proj dir: %v", err)
+	}
+	defer os.RemoveAll(projDir)
+
+	out, err := exec.Command(bin, "generate", "task", "recalc_stats", "--target", projDir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generate task failed: %v\noutput: %s", err, string(out))
+	}
+	for _, f := range []string{"app/tasks/recalc_stats.go", "cmd/tasks/main.go"} {
+		if _, err := os.Stat(filepath.Join(projDir, f)); err != nil {
+			t.Fatalf("expected %s: %v", f, err)
+		}
+	}
+	// a second task reuses the runner
+	if out, err := exec.Command(bin, "generate", "task", "cleanup", "--target", projDir).CombinedOutput(); err != nil || strings.Contains(string(out), "main.go") {
+		t.Fatalf("expected only the task file for a second task, got %v: %s", err, string(out))
+	}
+
+	task := func(args ...string) (string, error) {
+		cmd := exec.Command(bin, append([]string{"task"}, args...)...)
+		cmd.Dir = projDir
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	if out, err := task(); err != nil || !strings.Contains(out, "  cleanup\n  recalc_stats\n") {
+		t.Fatalf("expected the task list, got %v: %s", err, out)
+	}
+	if out, err := task("recalc_stats", "2024", "--dry-run"); err != nil || !strings.Contains(out, "recalc_stats [2024 --dry-run]") {
+		t.Fatalf("expected the task to run with its args, got %v: %s", err, out)
+	}
+	if out, err := task("missing"); err == nil || !strings.Contains(out, "unknown task") {
+		t.Fatalf("expected an unknown task error, got %v: %s", err, out)
+	}
+}
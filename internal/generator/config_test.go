@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI_GenerateConfig_CompilesAndLoadsDefaults(t *testing.T) {
+	repo := findRepoRoot()
+	modName, err := readModuleName(repo)
+	if err != nil {
+		t.Fatalf("read module name: %v", err)
+	}
+
+	// the project must live inside the module so the generated package builds
+	projDir, err := os.MkdirTemp(filepath.Join(repo, "examples"), "gen-config-*")
+	if err != nil {
+		t.Fatalf("mktemp proj dir: %v", err)
+	}
+	defer os.RemoveAll(projDir)
+
+	bin := filepath.Join(t.TempDir(), "flow-cli")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/flow")
+	build.Dir = repo
+	if bout, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build cli failed: %v\noutput: %s", err, string(bout))
+	}
+
+	gen := exec.Command(bin, "generate", "config", "--target", projDir)
+	gen.Dir = repo
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Fatalf("generate config failed: %v\n%s", err, string(out))
+	}
+	if _, err := os.Stat(filepath.Join(projDir, ".env.example")); err != nil {
+		t.Fatalf(".env.example not created: %v", err)
+	}
+
+	rel := strings.TrimPrefix(projDir, repo+string(os.PathSeparator))
+	cfgImport := modName + "/" + filepath.ToSlash(filepath.Join(rel, "app", "config"))
+	mainSrc := `package main
+
+import (
+    "fmt"
+
+    config "` + cfgImport + `"
+)
+
+func main() {
+    c := config.Load()
+    fmt.Println("ADDR:", c.Addr, "LEVEL:", c.LogLevel)
+}
+`
+	if err := os.WriteFile(filepath.Join(projDir, "main.go"), []byte(mainSrc), 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go")
+	cmd.Dir = projDir
+	// run with no config env set so defaults apply
+	env := []string{}
+	for _, kv := range os.Environ() {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case "ADDR", "DATABASE_DSN", "SESSION_SECRET", "LOG_LEVEL":
+			continue
+		}
+		env = append(env, kv)
+	}
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	t.Logf("run output: %s", string(out))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if !strings.Contains(string(out), "ADDR: :3000 LEVEL: info") {
+		t.Fatalf("unexpected output: %s", string(out))
+	}
+}
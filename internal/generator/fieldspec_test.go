@@ -2,6 +2,7 @@ package generator
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -76,3 +77,161 @@ func TestGenerateScaffoldWithFields(t *testing.T) {
 		t.Fatalf("migration missing stock column: %s", content)
 	}
 }
+
+func TestParseFieldSpecPostgresDialect(t *testing.T) {
+	fs, err := ParseFieldSpecDialect("published_at:datetime", DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.SQLType != "TIMESTAMPTZ" {
+		t.Fatalf("expected SQLType TIMESTAMPTZ, got %s", fs.SQLType)
+	}
+	fs, err = ParseFieldSpecDialect("score:float", DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.SQLType != "DOUBLE PRECISION" {
+		t.Fatalf("expected SQLType DOUBLE PRECISION, got %s", fs.SQLType)
+	}
+	if _, err := ParseFieldSpecDialect("title:string", "oracle"); err == nil {
+		t.Fatalf("expected error for unsupported dialect")
+	}
+}
+
+func TestGenerateScaffoldPostgresDialect(t *testing.T) {
+	td := t.TempDir()
+	opts := GenOptions{Dialect: DialectPostgres, NoViews: true}
+	created, err := GenerateScaffoldWithOptions(td, "event", opts, "starts_at:datetime")
+	if err != nil {
+		t.Fatalf("GenerateScaffoldWithOptions error: %v", err)
+	}
+	var up string
+	for _, p := range created {
+		if strings.HasSuffix(p, ".up.sql") {
+			up = p
+		}
+	}
+	b, err := os.ReadFile(up)
+	if err != nil {
+		t.Fatalf("read migration failed: %v", err)
+	}
+	content := string(b)
+	for _, want := range []string{"id SERIAL PRIMARY KEY", "created_at TIMESTAMPTZ NOT NULL", "starts_at TIMESTAMPTZ NOT NULL"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("migration missing %q: %s", want, content)
+		}
+	}
+}
+
+func TestGenerateScaffoldCompositeUnique(t *testing.T) {
+	td := t.TempDir()
+	opts := GenOptions{NoViews: true, UniqueConstraints: [][]string{ParseColumnList("user_id, slug")}}
+	created, err := GenerateScaffoldWithOptions(td, "post", opts, "user_id:int64", "slug:string")
+	if err != nil {
+		t.Fatalf("GenerateScaffoldWithOptions error: %v", err)
+	}
+	var up, down string
+	for _, p := range created {
+		switch {
+		case strings.HasSuffix(p, ".up.sql"):
+			up = p
+		case strings.HasSuffix(p, ".down.sql"):
+			down = p
+		}
+	}
+	ub, err := os.ReadFile(up)
+	if err != nil {
+		t.Fatalf("read up migration: %v", err)
+	}
+	if !strings.Contains(string(ub), "CONSTRAINT uq_posts_user_id_slug UNIQUE (user_id, slug)") {
+		t.Fatalf("up migration missing composite unique constraint: %s", ub)
+	}
+	db, err := os.ReadFile(down)
+	if err != nil {
+		t.Fatalf("read down migration: %v", err)
+	}
+	if !strings.Contains(string(db), "uq_posts_user_id_slug") {
+		t.Fatalf("down migration missing constraint name: %s", db)
+	}
+
+	opts.UniqueConstraints = [][]string{{"user_id", "missing"}}
+	if _, err := GenerateScaffoldWithOptions(t.TempDir(), "post", opts, "user_id:int64"); err == nil {
+		t.Fatalf("expected error for unknown unique constraint column")
+	}
+}
+
+func TestGenerateModelJSONTagOverride(t *testing.T) {
+	td := t.TempDir()
+	dst, err := GenerateModel(td, "post", "published_at:datetime,json=publishedAt", "secret:string,json=-")
+	if err != nil {
+		t.Fatalf("GenerateModel error: %v", err)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read model: %v", err)
+	}
+	s := string(b)
+	if !strings.Contains(s, "`bun:\"published_at\" json:\"publishedAt\"`") {
+		t.Fatalf("model missing customized json tag: %s", s)
+	}
+	if !strings.Contains(s, "`bun:\"secret\" json:\"-\"`") {
+		t.Fatalf("model missing omitted json tag: %s", s)
+	}
+}
+
+func TestGenerateModelSelfReferentialParent(t *testing.T) {
+	td := t.TempDir()
+	dst, err := GenerateModel(td, "category", "name:string", "parent:belongs_to:self")
+	if err != nil {
+		t.Fatalf("GenerateModel error: %v", err)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read model: %v", err)
+	}
+	s := string(b)
+	for _, want := range []string{
+		"ParentID *int64 `bun:\"parent_id\" json:\"parent_id,omitempty\"`",
+		"Parent *Category `bun:\"rel:belongs-to,join:parent_id=id\"",
+		"Children []*Category `bun:\"rel:has-many,join:id=parent_id\"",
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("model missing %q: %s", want, s)
+		}
+	}
+	if _, err := ParseFieldSpec("parent:belongs_to"); err == nil {
+		t.Fatalf("expected error for belongs_to without target")
+	}
+}
+
+func TestParseFieldSpec_RejectsUnsafeNames(t *testing.T) {
+	bad := []string{
+		"title; DROP TABLE:string",
+		"title; DROP TABLE",
+		"ti'tle:string",
+		"1title:string",
+		":string",
+		"author:belongs_to:users;--",
+		"user_id:int64,references=users.id);DROP TABLE users",
+	}
+	for _, in := range bad {
+		if _, err := ParseFieldSpec(in); err == nil {
+			t.Errorf("expected %q to be rejected", in)
+		} else if !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("expected a descriptive error for %q, got %v", in, err)
+		}
+	}
+	if _, err := ParseFieldSpec("published_at:datetime,references=users.id"); err != nil {
+		t.Fatalf("expected safe spec to parse: %v", err)
+	}
+}
+
+func TestGenerateModel_RejectsUnsafeName(t *testing.T) {
+	tmp := t.TempDir()
+	if _, err := GenerateModel(tmp, "post; DROP TABLE posts", "title:string"); err == nil {
+		t.Fatalf("expected unsafe model name to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "app")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written, stat err: %v", err)
+	}
+}
@@ -54,7 +54,7 @@ func TestGeneratedModelCompilesAndRuns(t *testing.T) {
 		t.Fatalf("generate model failed: %v\n%s", err, string(out))
 	}
 
-	// create main.go that uses the generated model's Save/Delete
+	// create main.go that uses the generated model's Save/Delete/Reload
 	rel := strings.TrimPrefix(projDir, repo+string(os.PathSeparator))
 	modelsImport := modName + "/" + filepath.ToSlash(filepath.Join(rel, "app", "models"))
 	mainSrc := `package main
@@ -93,9 +93,22 @@ func main() {
     }
     fmt.Println("FOUND:", got.Title)
 
+    got.Title = "compile-test-updated"
+    if err := got.Save(ctx, app); err != nil {
+        log.Fatalf("update: %v", err)
+    }
+    if err := p.Reload(ctx, app); err != nil {
+        log.Fatalf("reload: %v", err)
+    }
+    fmt.Println("RELOADED:", p.Title)
+
     if err := p.Delete(ctx, app); err != nil {
         log.Fatalf("delete: %v", err)
     }
+    if err := p.Reload(ctx, app); err == nil {
+        log.Fatalf("reload after delete: expected an error")
+    }
+    fmt.Println("DELETED")
 }
 `
 
@@ -111,7 +124,65 @@ func main() {
 	if err != nil {
 		t.Fatalf("run failed: %v", err)
 	}
-	if !strings.Contains(string(out), "FOUND: compile-test-hello") {
-		t.Fatalf("unexpected output: %s", string(out))
+	for _, want := range []string{"FOUND: compile-test-hello", "RELOADED: compile-test-updated", "DELETED"} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("expected %q in output: %s", want, string(out))
+		}
+	}
+}
+
+func TestGeneratedPolicyCompilesAndDenies(t *testing.T) {
+	repo := findRepoRoot()
+	modName, err := readModuleName(repo)
+	if err != nil {
+		t.Fatalf("read module name: %v", err)
+	}
+	projDir, err := os.MkdirTemp(filepath.Join(repo, "examples"), "gen-policy-*")
+	if err != nil {
+		t.Fatalf("mktemp proj dir: %v", err)
+	}
+	defer os.RemoveAll(projDir)
+
+	dst, err := GeneratePolicy(projDir, "post", GenOptions{})
+	if err != nil {
+		t.Fatalf("generate policy: %v", err)
+	}
+	if filepath.Base(dst) != "post_policy.go" {
+		t.Fatalf("unexpected policy path %s", dst)
+	}
+
+	rel := strings.TrimPrefix(projDir, repo+string(os.PathSeparator))
+	policiesImport := modName + "/" + filepath.ToSlash(filepath.Join(rel, "app", "policies"))
+	mainSrc := `package main
+
+import (
+    "errors"
+    "fmt"
+
+    flow "` + modName + `/pkg/flow"
+    policies "` + policiesImport + `"
+)
+
+func main() {
+    var p flow.Policy = policies.PostPolicy{}
+    for _, action := range []string{"view", "edit", "destroy", "publish"} {
+        err := flow.Authorize(p, action, "alice", nil)
+        fmt.Println(action, errors.Is(err, flow.ErrForbidden))
+    }
+}
+`
+	if err := os.WriteFile(filepath.Join(projDir, "main.go"), []byte(mainSrc), 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+	cmd := exec.Command("go", "run", "main.go")
+	cmd.Dir = projDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run failed: %v\n%s", err, string(out))
+	}
+	for _, want := range []string{"view true", "edit true", "destroy true", "publish true"} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("expected %q (denied by default) in output: %s", want, string(out))
+		}
 	}
 }
@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
-	"time"
 )
 
 // generateFile renders tmpl with data and writes it to dstPath. It will
@@ -47,20 +48,119 @@ type GenOptions struct {
 	Force          bool // overwrite existing files
 	SkipMigrations bool // don't generate migration files
 	NoViews        bool // don't generate view files
+	// Dialect selects SQL column types for generated migrations: sqlite
+	// (the default when empty), postgres or mysql.
+	Dialect string
+	// UniqueConstraints lists multi-column unique constraints to add to the
+	// generated table, eg. {{"user_id", "slug"}}. Every column must be one of
+	// the parsed fields.
+	UniqueConstraints [][]string
+	// API generates a JSON controller backed by the model (with a
+	// Register<Controller>Routes helper) instead of an HTML one, and skips
+	// views. The target must be inside a Go module.
+	API bool
+}
+
+// uniqueConstraintName returns the constraint name used for a composite
+// unique constraint on table, eg. uq_posts_user_id_slug.
+func uniqueConstraintName(table string, cols []string) string {
+	return "uq_" + table + "_" + strings.Join(cols, "_")
+}
+
+// validateUniqueConstraints checks that every column named in groups is a
+// parsed field (or one of the implicit id/created_at/updated_at columns).
+func validateUniqueConstraints(specs []FieldSpec, groups [][]string) error {
+	known := map[string]bool{"id": true, "created_at": true, "updated_at": true}
+	for _, fs := range specs {
+		known[fs.Name] = true
+	}
+	for _, cols := range groups {
+		if len(cols) == 0 {
+			return fmt.Errorf("generator: empty unique constraint")
+		}
+		for _, c := range cols {
+			if !known[c] {
+				return fmt.Errorf("generator: unique constraint column %q is not a field", c)
+			}
+		}
+	}
+	return nil
 }
 
 // GenerateControllerWithOptions generates a controller honoring options.
 func GenerateControllerWithOptions(projectRoot, name string, opts GenOptions) (string, error) {
 	cname := Title(name) + "Controller"
 	dst := filepath.Join(projectRoot, "app", "controllers", name+"_controller.go")
+	if err := ValidateIdentifier("controller", name); err != nil {
+		return dst, err
+	}
 	data := map[string]string{
 		"Package":    "controllers",
 		"Controller": cname,
 		"Name":       name,
 	}
+	if opts.API {
+		modelsImport, err := ImportPath(filepath.Join(projectRoot, "app", "models"))
+		if err != nil {
+			return dst, err
+		}
+		data["Model"] = Title(name)
+		data["Resource"] = TableName(name)
+		data["ModelsImport"] = modelsImport
+		return dst, generateFile(apiControllerTmpl, data, dst, opts.Force)
+	}
 	return dst, generateFile(controllerTmpl, data, dst, opts.Force)
 }
 
+// GeneratePolicy creates app/policies/<name>_policy.go with deny-by-default
+// CanView, CanEdit and CanDestroy stubs and the Can method flow.Authorize
+// calls.
+func GeneratePolicy(projectRoot, name string, opts GenOptions) (string, error) {
+	dst := filepath.Join(projectRoot, "app", "policies", strings.ToLower(name)+"_policy.go")
+	if err := ValidateIdentifier("policy", name); err != nil {
+		return dst, err
+	}
+	data := map[string]string{
+		"Package": "policies",
+		"Policy":  Title(name) + "Policy",
+		"Model":   Title(name),
+	}
+	return dst, generateFile(policyTmpl, data, dst, opts.Force)
+}
+
+// GenerateTask creates app/tasks/<name>.go registering the task name with
+// flow.RegisterTask, and the cmd/tasks runner `flow task` invokes unless it
+// already exists. It returns the files written.
+func GenerateTask(projectRoot, name string, opts GenOptions) ([]string, error) {
+	name = strings.ToLower(name)
+	if err := ValidateIdentifier("task", name); err != nil {
+		return nil, err
+	}
+	tasksDir := filepath.Join(projectRoot, "app", "tasks")
+	tasksImport, err := ImportPath(tasksDir)
+	if err != nil {
+		return nil, err
+	}
+	var created []string
+	dst := filepath.Join(tasksDir, name+".go")
+	data := map[string]string{
+		"Name": name,
+		"Func": "run" + strings.ReplaceAll(Title(strings.ReplaceAll(name, "_", " ")), " ", ""),
+	}
+	if err := generateFile(taskTmpl, data, dst, opts.Force); err != nil {
+		return created, err
+	}
+	created = append(created, dst)
+	runner := filepath.Join(projectRoot, "cmd", "tasks", "main.go")
+	if _, err := os.Stat(runner); err == nil {
+		return created, nil
+	}
+	if err := generateFile(taskRunnerTmpl, map[string]string{"TasksImport": tasksImport}, runner, false); err != nil {
+		return created, err
+	}
+	return append(created, runner), nil
+}
+
 // GenerateModel creates a simple model file under app/models.
 func GenerateModel(projectRoot, name string, fields ...string) (string, error) {
 	return GenerateModelWithOptions(projectRoot, name, GenOptions{}, fields...)
@@ -70,12 +170,15 @@ func GenerateModel(projectRoot, name string, fields ...string) (string, error) {
 func GenerateModelWithOptions(projectRoot, name string, opts GenOptions, fields ...string) (string, error) {
 	mname := Title(name)
 	dst := filepath.Join(projectRoot, "app", "models", strings.ToLower(name)+".go")
+	if err := ValidateIdentifier("model", name); err != nil {
+		return dst, err
+	}
 
 	// parse fields and build struct lines and migration columns using FieldSpec
 	var fieldsCodeLines []string
 	var columnsLines []string
 	needTime := false
-	specs, err := ParseFields(fields)
+	specs, err := ParseFieldsDialect(fields, opts.Dialect)
 	if err != nil {
 		return dst, err
 	}
@@ -83,13 +186,20 @@ func GenerateModelWithOptions(projectRoot, name string, opts GenOptions, fields
 		if strings.Contains(fs.GoType, "time.Time") || strings.Contains(fs.GoType, "*time.Time") {
 			needTime = true
 		}
-		// struct tag: bun and json; use omitempty for nullable
+		// struct tag: bun uses the column name, json uses the configured
+		// name (json=...) or the column name; use omitempty for nullable
 		jsonTag := fs.Name
-		if fs.Nullable {
+		if fs.JSONName != "" {
+			jsonTag = fs.JSONName
+		}
+		if fs.Nullable && jsonTag != "-" {
 			jsonTag = jsonTag + ",omitempty"
 		}
 		tag := fmt.Sprintf("`bun:\"%s\" json:\"%s\"`", fs.Name, jsonTag)
 		fieldsCodeLines = append(fieldsCodeLines, fmt.Sprintf("    %s %s %s", fs.GoName, fs.GoType, tag))
+		if fs.Association == "belongs_to" {
+			fieldsCodeLines = append(fieldsCodeLines, associationLines(mname, fs)...)
+		}
 
 		// column SQL line (skip id/created/updated handled separately)
 		notnull := ""
@@ -115,22 +225,73 @@ func GenerateModelWithOptions(projectRoot, name string, opts GenOptions, fields
 		cols = ",\n" + strings.Join(columnsLines, ",\n")
 	}
 
-	extraImports := ""
+	// Save, Delete and Reload always need context and flow
+	imports := []string{"context", "github.com/dministrator/flow/pkg/flow"}
 	if needTime {
-		extraImports = "\n    \"time\""
+		imports = append(imports, "time")
 	}
 
 	data := map[string]string{
-		"Package":      "models",
-		"Model":        mname,
-		"FieldsCode":   fieldsCode,
-		"Columns":      cols,
-		"ExtraImports": extraImports,
+		"Package":    "models",
+		"Model":      mname,
+		"FieldsCode": fieldsCode,
+		"Columns":    cols,
+		"Imports":    importLines(imports),
 	}
 
 	return dst, generateFile(bunModelTmpl, data, dst, opts.Force)
 }
 
+// importLines renders import paths as the sorted, quoted lines of an
+// import block.
+func importLines(paths []string) string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	lines := make([]string, len(sorted))
+	for i, p := range sorted {
+		lines[i] = "    " + strconv.Quote(p)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// associationLines returns the bun relation fields for a belongs_to field
+// of model. A self reference also gets the inverse has-many Children field.
+func associationLines(model string, fs FieldSpec) []string {
+	target := fs.Target
+	if fs.SelfRef {
+		target = model
+	}
+	jsonName := strings.TrimSuffix(fs.Name, "_id")
+	lines := []string{fmt.Sprintf("    %s *%s `bun:\"rel:belongs-to,join:%s=id\" json:\"%s,omitempty\"`", fs.AssocName, target, fs.Name, jsonName)}
+	if fs.SelfRef {
+		lines = append(lines, fmt.Sprintf("    Children []*%s `bun:\"rel:has-many,join:id=%s\" json:\"children,omitempty\"`", model, fs.Name))
+	}
+	return lines
+}
+
+// GenerateConfig creates app/config/config.go (a Config struct with an
+// env-driven Load) and a .env.example at the project root. The project name
+// used in defaults is the base name of projectRoot.
+func GenerateConfig(projectRoot string, opts GenOptions) ([]string, error) {
+	abs, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{"Name": strings.ToLower(filepath.Base(abs))}
+	cfgPath := filepath.Join(projectRoot, "app", "config", "config.go")
+	envPath := filepath.Join(projectRoot, ".env.example")
+	var created []string
+	if err := generateFile(configTmpl, data, cfgPath, opts.Force); err != nil {
+		return created, err
+	}
+	created = append(created, cfgPath)
+	if err := generateFile(envExampleTmpl, data, envPath, opts.Force); err != nil {
+		return created, err
+	}
+	created = append(created, envPath)
+	return created, nil
+}
+
 // GenerateScaffold generates controller + model + basic views.
 func GenerateScaffold(projectRoot, name string, fields ...string) ([]string, error) {
 	return GenerateScaffoldWithOptions(projectRoot, name, GenOptions{}, fields...)
@@ -139,6 +300,18 @@ func GenerateScaffold(projectRoot, name string, fields ...string) ([]string, err
 // GenerateScaffoldWithOptions generates controller + model + basic views and migrations honoring options.
 func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fields ...string) ([]string, error) {
 	var created []string
+	// validate the name, field specs and constraints before writing anything
+	if err := ValidateIdentifier("resource", name); err != nil {
+		return created, err
+	}
+	specs, err := ParseFieldsDialect(fields, opts.Dialect)
+	if err != nil {
+		return created, err
+	}
+	if err := validateUniqueConstraints(specs, opts.UniqueConstraints); err != nil {
+		return created, err
+	}
+
 	// controller
 	cpath, err := GenerateControllerWithOptions(projectRoot, name, opts)
 	if err != nil {
@@ -153,8 +326,8 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 	}
 	created = append(created, mpath)
 
-	// views
-	if !opts.NoViews {
+	// views (API scaffolds have none)
+	if !opts.NoViews && !opts.API {
 		viewsDir := filepath.Join(projectRoot, "app", "views", name)
 		if err := os.MkdirAll(viewsDir, 0o755); err != nil {
 			return created, err
@@ -177,7 +350,10 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 		if err := os.MkdirAll(migDir, 0o755); err != nil {
 			return created, err
 		}
-		ts := TimestampNow()
+		ts, err := NextMigrationTimestamp(migDir)
+		if err != nil {
+			return created, err
+		}
 		table := TableName(name)
 		upName := fmt.Sprintf("%s_create_%s.up.sql", ts, table)
 		downName := fmt.Sprintf("%s_create_%s.down.sql", ts, table)
@@ -186,11 +362,7 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 
 		// compute columns SQL for migration based on fields
 		var columnsLines []string
-		specs2, err := ParseFields(fields)
-		if err != nil {
-			return created, err
-		}
-		for _, fs := range specs2 {
+		for _, fs := range specs {
 			notnull := ""
 			if !fs.Nullable {
 				notnull = " NOT NULL"
@@ -204,6 +376,9 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 			}
 			columnsLines = append(columnsLines, col)
 		}
+		for _, uc := range opts.UniqueConstraints {
+			columnsLines = append(columnsLines, fmt.Sprintf("    CONSTRAINT %s UNIQUE (%s)", uniqueConstraintName(table, uc), strings.Join(uc, ", ")))
+		}
 		cols := ""
 		if len(columnsLines) > 0 {
 			cols = ",\n" + strings.Join(columnsLines, ",\n")
@@ -212,13 +387,18 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 		// build extras: indexes (CREATE INDEX) and corresponding DROP INDEX for down
 		var extrasUpLines []string
 		var extrasDownLines []string
-		for _, fs := range specs2 {
+		for _, fs := range specs {
 			if fs.Index {
 				idxName := fmt.Sprintf("idx_%s_%s", table, fs.Name)
 				extrasUpLines = append(extrasUpLines, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s);", idxName, table, fs.Name))
 				extrasDownLines = append(extrasDownLines, fmt.Sprintf("DROP INDEX IF EXISTS %s;", idxName))
 			}
 		}
+		// constraints go away with the table; the statement is kept for
+		// migrations that alter rather than drop it (not supported by sqlite)
+		for _, uc := range opts.UniqueConstraints {
+			extrasDownLines = append(extrasDownLines, fmt.Sprintf("-- ALTER TABLE %s DROP CONSTRAINT %s;", table, uniqueConstraintName(table, uc)))
+		}
 		extrasUp := ""
 		if len(extrasUpLines) > 0 {
 			extrasUp = strings.Join(extrasUpLines, "\n") + "\n"
@@ -229,7 +409,15 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 		}
 
 		// render migration templates (include extras for indexes)
-		upData := map[string]string{"Timestamp": ts, "Table": table, "Columns": cols, "ExtrasUp": extrasUp}
+		dialect, _ := NormalizeDialect(opts.Dialect)
+		upData := map[string]string{
+			"Timestamp": ts,
+			"Table":     table,
+			"IDColumn":  IDColumn(dialect),
+			"TimeType":  TimestampType(dialect),
+			"Columns":   cols,
+			"ExtrasUp":  extrasUp,
+		}
 		downData := map[string]string{"Timestamp": ts, "Table": table, "ExtrasDown": extrasDown}
 		if err := generateFile(migrationUpTmpl, upData, upPath, opts.Force); err != nil {
 			return created, err
@@ -240,7 +428,29 @@ func GenerateScaffoldWithOptions(projectRoot, name string, opts GenOptions, fiel
 		created = append(created, upPath, downPath)
 	}
 
-	// small delay to avoid duplicate timestamps when called rapidly
-	time.Sleep(1 * time.Second)
 	return created, nil
 }
+
+// GenerateMigration creates an empty <timestamp>_<name>.up.sql and
+// .down.sql pair in dir for hand-written changes (indexes, data fixes) and
+// returns their paths. The timestamp comes from NextMigrationTimestamp, so
+// it always sorts after the migrations already in dir.
+func GenerateMigration(dir, name string) ([]string, error) {
+	if err := ValidateIdentifier("migration", name); err != nil {
+		return nil, err
+	}
+	ts, err := NextMigrationTimestamp(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{"Timestamp": ts, "Name": name}
+	upPath := filepath.Join(dir, fmt.Sprintf("%s_%s.up.sql", ts, name))
+	downPath := filepath.Join(dir, fmt.Sprintf("%s_%s.down.sql", ts, name))
+	if err := generateFile(migrationBlankUpTmpl, data, upPath, false); err != nil {
+		return nil, err
+	}
+	if err := generateFile(migrationBlankDownTmpl, data, downPath, false); err != nil {
+		return []string{upPath}, err
+	}
+	return []string{upPath, downPath}, nil
+}
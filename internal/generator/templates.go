@@ -23,6 +23,176 @@ func (c *{{.Controller}}) Show(ctx *flow.Context) {
     id := ctx.Param("id")
     ctx.JSON(200, map[string]string{"id": id})
 }
+
+// Update handles both PUT (full replacement) and PATCH (partial update).
+// This controller has no model to persist; "flow generate scaffold --api"
+// generates a complete Update that binds PATCH bodies with ctx.BindPatch
+// and writes the returned columns with flow.UpdateColumns, and replaces the
+// record with flow.Update for PUT.
+func (c *{{.Controller}}) Update(ctx *flow.Context) {
+    id := ctx.Param("id")
+    if ctx.IsPatch() {
+        ctx.JSON(200, map[string]string{"action": "patch", "id": id})
+        return
+    }
+    ctx.JSON(200, map[string]string{"action": "update", "id": id})
+}
+`
+
+// apiControllerTmpl is the JSON controller generated by scaffold --api. It
+// implements flow.Resource on top of the generated model; the HTML-only New
+// and Edit actions respond 404.
+var apiControllerTmpl = `// Code generated by flow generate; DO NOT EDIT.
+package {{.Package}}
+
+import (
+    "database/sql"
+    "errors"
+    "net/http"
+    "slices"
+    "strconv"
+
+    flow "github.com/dministrator/flow/pkg/flow"
+    models "{{.ModelsImport}}"
+)
+
+// {{.Controller}} is a JSON API controller generated by Flow.
+type {{.Controller}} struct{ *flow.Controller }
+
+func New{{.Controller}}(app *flow.App) *{{.Controller}} {
+    return &{{.Controller}}{Controller: flow.NewController(app)}
+}
+
+// Register{{.Controller}}Routes registers the RESTful /{{.Resource}} routes on r.
+func Register{{.Controller}}Routes(r *flow.Router, app *flow.App) error {
+    return r.Resources("{{.Resource}}", New{{.Controller}}(app))
+}
+
+// Index lists {{.Resource}} a page at a time (?page=N&per_page=M).
+func (c *{{.Controller}}) Index(ctx *flow.Context) {
+    page, perPage := ctx.PageParams()
+    var items []models.{{.Model}}
+    p, err := flow.Paginate(ctx.R.Context(), c.App, &items, page, perPage)
+    if err != nil {
+        _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+        return
+    }
+    if items == nil {
+        items = []models.{{.Model}}{}
+    }
+    _ = ctx.JSON(http.StatusOK, map[string]interface{}{"items": items, "pagination": p})
+}
+
+// New has no JSON representation.
+func (c *{{.Controller}}) New(ctx *flow.Context) {
+    _ = ctx.JSONError(http.StatusNotFound, "not found")
+}
+
+// Create inserts a {{.Name}} from the JSON body and responds 201.
+func (c *{{.Controller}}) Create(ctx *flow.Context) {
+    var m models.{{.Model}}
+    if err := ctx.BindJSON(&m); err != nil {
+        _ = ctx.JSONError(http.StatusBadRequest, err.Error())
+        return
+    }
+    m.ID = 0
+    if err := flow.Insert(ctx.R.Context(), c.App, &m); err != nil {
+        _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+        return
+    }
+    _ = ctx.JSON(http.StatusCreated, &m)
+}
+
+// Show responds with a single {{.Name}}.
+func (c *{{.Controller}}) Show(ctx *flow.Context) {
+    m, ok := c.find(ctx)
+    if !ok {
+        return
+    }
+    _ = ctx.JSON(http.StatusOK, m)
+}
+
+// Edit has no JSON representation.
+func (c *{{.Controller}}) Edit(ctx *flow.Context) {
+    _ = ctx.JSONError(http.StatusNotFound, "not found")
+}
+
+// Update replaces a {{.Name}} (PUT) or updates only the fields present in
+// the body (PATCH).
+func (c *{{.Controller}}) Update(ctx *flow.Context) {
+    m, ok := c.find(ctx)
+    if !ok {
+        return
+    }
+    id := m.ID
+    if ctx.IsPatch() {
+        cols, err := ctx.BindPatch(m)
+        if err != nil {
+            _ = ctx.JSONError(http.StatusBadRequest, err.Error())
+            return
+        }
+        m.ID = id
+        // BindPatch reports every json-mapped column; the key and
+        // timestamps are not client-writable
+        cols = slices.DeleteFunc(cols, func(col string) bool {
+            switch col {
+            case "id", "created_at", "updated_at", "deleted_at":
+                return true
+            }
+            return false
+        })
+        if len(cols) > 0 {
+            if err := flow.UpdateColumns(ctx.R.Context(), c.App, m, cols...); err != nil {
+                _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+                return
+            }
+        }
+    } else {
+        if err := ctx.BindJSON(m); err != nil {
+            _ = ctx.JSONError(http.StatusBadRequest, err.Error())
+            return
+        }
+        m.ID = id
+        if err := flow.Update(ctx.R.Context(), c.App, m); err != nil {
+            _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+            return
+        }
+    }
+    _ = ctx.JSON(http.StatusOK, m)
+}
+
+// Destroy deletes a {{.Name}} and responds 204.
+func (c *{{.Controller}}) Destroy(ctx *flow.Context) {
+    m, ok := c.find(ctx)
+    if !ok {
+        return
+    }
+    if err := flow.Delete(ctx.R.Context(), c.App, m); err != nil {
+        _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+        return
+    }
+    ctx.Status(http.StatusNoContent)
+}
+
+// find loads the {{.Name}} named by the :id param, writing 404 when it does
+// not exist.
+func (c *{{.Controller}}) find(ctx *flow.Context) (*models.{{.Model}}, bool) {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        _ = ctx.JSONError(http.StatusNotFound, "{{.Name}} not found")
+        return nil, false
+    }
+    var m models.{{.Model}}
+    if err := flow.FindByPK(ctx.R.Context(), c.App, &m, id); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            _ = ctx.JSONError(http.StatusNotFound, "{{.Name}} not found")
+        } else {
+            _ = ctx.JSONError(http.StatusInternalServerError, err.Error())
+        }
+        return nil, false
+    }
+    return &m, true
+}
 `
 
 // removed unused generated model template (bunModelTmpl is used instead)
@@ -34,9 +204,7 @@ var bunModelTmpl = `// Code generated by flow generate; DO NOT EDIT.
 package {{.Package}}
 
 import (
-    "context"
-    "github.com/dministrator/flow/pkg/flow"
-{{.ExtraImports}}
+{{.Imports}}
 )
 
 // {{.Model}} is a generated model using bun struct tags.
@@ -57,14 +225,106 @@ func (m *{{.Model}}) Save(ctx context.Context, app *flow.App) error {
 func (m *{{.Model}}) Delete(ctx context.Context, app *flow.App) error {
     return flow.Delete(ctx, app, m)
 }
+
+// Reload refreshes the model from the database by its ID.
+func (m *{{.Model}}) Reload(ctx context.Context, app *flow.App) error {
+    return flow.FindByPK(ctx, app, m, m.ID)
+}
+`
+
+// policyTmpl is an authorization policy with deny-by-default stubs. It
+// satisfies flow.Policy without importing flow.
+var policyTmpl = `package {{.Package}}
+
+// {{.Policy}} decides what a user may do with a {{.Model}}. Every action is
+// denied until the stubs below are filled in.
+type {{.Policy}} struct{}
+
+// CanView reports whether user may see record.
+func (p {{.Policy}}) CanView(user, record interface{}) bool {
+    return false
+}
+
+// CanEdit reports whether user may change record.
+func (p {{.Policy}}) CanEdit(user, record interface{}) bool {
+    return false
+}
+
+// CanDestroy reports whether user may delete record.
+func (p {{.Policy}}) CanDestroy(user, record interface{}) bool {
+    return false
+}
+
+// Can dispatches flow.Authorize actions ("view", "edit", "destroy") to the
+// methods above; unknown actions are denied.
+func (p {{.Policy}}) Can(action string, user, record interface{}) bool {
+    switch action {
+    case "view":
+        return p.CanView(user, record)
+    case "edit":
+        return p.CanEdit(user, record)
+    case "destroy":
+        return p.CanDestroy(user, record)
+    }
+    return false
+}
+`
+
+// taskTmpl is an app task registered from init and run with flow task.
+var taskTmpl = `package tasks
+
+import (
+    "context"
+    "fmt"
+
+    flow "github.com/dministrator/flow/pkg/flow"
+)
+
+func init() {
+    flow.RegisterTask("{{.Name}}", {{.Func}})
+}
+
+// {{.Func}} implements ` + "`flow task {{.Name}} [args]`" + `.
+func {{.Func}}(ctx context.Context, args []string) error {
+    // TODO: do the work
+    fmt.Println("{{.Name}}", args)
+    return nil
+}
+`
+
+// taskRunnerTmpl is the project's task runner: it links in app/tasks and
+// hands its arguments to flow.TaskMain.
+var taskRunnerTmpl = `// Command tasks runs the app tasks registered in app/tasks; ` + "`flow task`" + `
+// invokes it. Without arguments it lists the tasks.
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/signal"
+
+    flow "github.com/dministrator/flow/pkg/flow"
+    _ "{{.TasksImport}}"
+)
+
+func main() {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+    if err := flow.TaskMain(ctx, os.Args[1:], os.Stdout); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+}
 `
 
 var migrationUpTmpl = `-- Migration: {{.Timestamp}}_create_{{.Table}}.up.sql
 -- Generated by flow
 CREATE TABLE IF NOT EXISTS {{.Table}} (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    created_at DATETIME NOT NULL,
-    updated_at DATETIME NOT NULL
+    {{.IDColumn}},
+    created_at {{.TimeType}} NOT NULL,
+    updated_at {{.TimeType}} NOT NULL,
+    deleted_at {{.TimeType}}
 {{.Columns}}
 );
 {{.ExtrasUp}}
@@ -76,6 +336,14 @@ var migrationDownTmpl = `-- Migration: {{.Timestamp}}_create_{{.Table}}.down.sql
 DROP TABLE IF EXISTS {{.Table}};
 `
 
+var migrationBlankUpTmpl = `-- Migration: {{.Timestamp}}_{{.Name}}.up.sql
+-- Generated by flow
+`
+
+var migrationBlankDownTmpl = `-- Migration: {{.Timestamp}}_{{.Name}}.down.sql
+-- Generated by flow
+`
+
 var viewIndexTmpl = `<h1>{{.Title}} index</h1>
 <ul>
 {{range .Items}}  <li>{{.}}</li>
@@ -95,3 +363,49 @@ var viewEditTmpl = `<h1>Edit {{.Title}}</h1>
     <!-- TODO: fields -->
     <button type="submit">Save</button>
 </form>`
+
+var configTmpl = `// Code generated by flow generate; DO NOT EDIT.
+package config
+
+import (
+    "os"
+)
+
+// Config holds application settings loaded from the environment.
+type Config struct {
+    // Addr is the HTTP listen address (ADDR).
+    Addr string
+    // DatabaseDSN is the database connection string (DATABASE_DSN).
+    DatabaseDSN string
+    // SessionSecret signs session cookies (SESSION_SECRET). Set it in
+    // production; an empty secret makes the app use a random one.
+    SessionSecret string
+    // LogLevel controls logging verbosity (LOG_LEVEL).
+    LogLevel string
+}
+
+// Load reads configuration from environment variables, falling back to
+// development defaults for anything unset.
+func Load() Config {
+    return Config{
+        Addr:          getenv("ADDR", ":3000"),
+        DatabaseDSN:   getenv("DATABASE_DSN", "file:{{.Name}}.db?cache=shared"),
+        SessionSecret: getenv("SESSION_SECRET", ""),
+        LogLevel:      getenv("LOG_LEVEL", "info"),
+    }
+}
+
+func getenv(key, def string) string {
+    if v, ok := os.LookupEnv(key); ok && v != "" {
+        return v
+    }
+    return def
+}
+`
+
+var envExampleTmpl = `# Example environment for {{.Name}}. Copy to .env and adjust.
+ADDR=:3000
+DATABASE_DSN=file:{{.Name}}.db?cache=shared
+SESSION_SECRET=change-me
+LOG_LEVEL=info
+`
@@ -2,6 +2,9 @@ package generator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +18,34 @@ func TimestampNow() string {
 	return time.Now().UTC().Format("20060102150405")
 }
 
+// NextMigrationTimestamp returns a TimestampNow-style timestamp that sorts
+// after every migration already in dir. When a migration in dir already
+// uses the current second (or a later one) the result is bumped a second
+// past it, so migrations created in quick succession never share a prefix.
+// A missing dir yields TimestampNow.
+func NextMigrationTimestamp(dir string) (string, error) {
+	const layout = "20060102150405"
+	ts := time.Now().UTC().Truncate(time.Second)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("generator: read migrations dir: %w", err)
+	}
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok || len(prefix) != len(layout) {
+			continue
+		}
+		t, err := time.Parse(layout, prefix)
+		if err != nil {
+			continue
+		}
+		if !ts.After(t) {
+			ts = t.Add(time.Second)
+		}
+	}
+	return ts.Format(layout), nil
+}
+
 // TableName returns a simple pluralized table name for a resource.
 // It's intentionally naive: if name ends with 's' it is returned as-is,
 // otherwise we append 's'. This is sufficient for prototype scaffolding.
@@ -26,6 +57,93 @@ func TableName(name string) string {
 	return name + "s"
 }
 
+// identRe matches names that are safe to splice into generated SQL and Go
+// code as table or column identifiers.
+var identRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateIdentifier rejects names that are not plain identifiers (letters,
+// digits and underscores, not starting with a digit). The generator builds
+// SQL by concatenating names, so anything else, eg. "title; DROP TABLE",
+// could produce malformed or injected statements. kind names the offending
+// input in the error (eg. "field", "model").
+func ValidateIdentifier(kind, name string) error {
+	if !identRe.MatchString(name) {
+		return fmt.Errorf("generator: invalid %s name %q: use letters, digits and underscores, not starting with a digit", kind, name)
+	}
+	return nil
+}
+
+// Supported SQL dialects for generated migrations.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+// dialectTypes overrides the SQLite column type for a base type kind. Kinds
+// missing from a dialect's map use the SQLite type.
+var dialectTypes = map[string]map[string]string{
+	DialectPostgres: {
+		"int64":    "BIGINT",
+		"float":    "DOUBLE PRECISION",
+		"datetime": "TIMESTAMPTZ",
+	},
+	DialectMySQL: {
+		"int":   "INT",
+		"int64": "BIGINT",
+		"float": "DOUBLE",
+	},
+}
+
+// sqliteTypes maps base type kinds to SQLite column types.
+var sqliteTypes = map[string]string{
+	"string":   "TEXT",
+	"int":      "INTEGER",
+	"int64":    "INTEGER",
+	"bool":     "BOOLEAN",
+	"float":    "REAL",
+	"datetime": "DATETIME",
+}
+
+// NormalizeDialect validates dialect and returns its canonical name. An
+// empty dialect means sqlite; "sqlite3", "postgresql" and "pg" are accepted
+// as aliases.
+func NormalizeDialect(dialect string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(dialect)) {
+	case "", "sqlite", "sqlite3":
+		return DialectSQLite, nil
+	case "postgres", "postgresql", "pg":
+		return DialectPostgres, nil
+	case "mysql":
+		return DialectMySQL, nil
+	}
+	return "", fmt.Errorf("generator: unsupported dialect %q (want sqlite, postgres or mysql)", dialect)
+}
+
+// columnType returns the SQL type for a base type kind under dialect.
+func columnType(dialect, kind string) string {
+	if t, ok := dialectTypes[dialect][kind]; ok {
+		return t
+	}
+	return sqliteTypes[kind]
+}
+
+// IDColumn returns the primary key column definition for dialect.
+func IDColumn(dialect string) string {
+	switch dialect {
+	case DialectPostgres:
+		return "id SERIAL PRIMARY KEY"
+	case DialectMySQL:
+		return "id BIGINT AUTO_INCREMENT PRIMARY KEY"
+	}
+	return "id INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+// TimestampType returns the column type used for created_at/updated_at.
+func TimestampType(dialect string) string {
+	return columnType(dialect, "datetime")
+}
+
 // FieldSpec describes a parsed field specification used by generators.
 type FieldSpec struct {
 	Name       string // original name (snake/camel as provided)
@@ -38,21 +156,40 @@ type FieldSpec struct {
 	Unique     bool
 	Index      bool
 	References string
+	JSONName   string // json tag name override; "-" omits the field
 	Size       int
 	Precision  int
 	Scale      int
+
+	// Association is "belongs_to" for association fields such as
+	// author:belongs_to:user. Name is then the FK column (author_id) and
+	// AssocName the Go name of the relation field (Author).
+	Association string
+	AssocName   string
+	Target      string // referenced model type; empty when SelfRef
+	SelfRef     bool   // association target is the model being generated
 }
 
-// ParseFields parses multiple field spec strings into FieldSpec objects.
+// ParseFields parses multiple field spec strings into FieldSpec objects
+// using SQLite column types.
 // Expected forms:
 //
 //	name             (defaults to string)
 //	name:type        (e.g. age:int)
 //	name:type,opt1,opt2=val (e.g. price:decimal(10,2),default=0,nullable,index)
 func ParseFields(inputs []string) ([]FieldSpec, error) {
+	return ParseFieldsDialect(inputs, DialectSQLite)
+}
+
+// ParseFieldsDialect is like ParseFields but resolves SQL types for dialect.
+func ParseFieldsDialect(inputs []string, dialect string) ([]FieldSpec, error) {
+	dialect, err := NormalizeDialect(dialect)
+	if err != nil {
+		return nil, err
+	}
 	out := make([]FieldSpec, 0, len(inputs))
 	for _, in := range inputs {
-		fs, err := ParseFieldSpec(in)
+		fs, err := ParseFieldSpecDialect(in, dialect)
 		if err != nil {
 			return nil, err
 		}
@@ -61,9 +198,20 @@ func ParseFields(inputs []string) ([]FieldSpec, error) {
 	return out, nil
 }
 
-// ParseFieldSpec parses a single field specification string.
+// ParseFieldSpec parses a single field specification string using SQLite
+// column types.
 func ParseFieldSpec(input string) (FieldSpec, error) {
+	return ParseFieldSpecDialect(input, DialectSQLite)
+}
+
+// ParseFieldSpecDialect parses a single field specification string,
+// resolving SQL types for dialect (eg. datetime is TIMESTAMPTZ on postgres).
+func ParseFieldSpecDialect(input, dialect string) (FieldSpec, error) {
 	var fs FieldSpec
+	dialect, err := NormalizeDialect(dialect)
+	if err != nil {
+		return fs, err
+	}
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return fs, nil
@@ -71,6 +219,9 @@ func ParseFieldSpec(input string) (FieldSpec, error) {
 	// split name and rest
 	parts := strings.SplitN(input, ":", 2)
 	name := strings.TrimSpace(parts[0])
+	if err := ValidateIdentifier("field", name); err != nil {
+		return fs, err
+	}
 	fs.Name = name
 	fs.GoName = Title(name)
 	var rest string
@@ -95,26 +246,51 @@ func ParseFieldSpec(input string) (FieldSpec, error) {
 	switch strings.ToLower(base) {
 	case "string", "text":
 		fs.GoType = "string"
-		fs.SQLType = "TEXT"
+		fs.SQLType = columnType(dialect, "string")
 	case "int", "integer":
 		fs.GoType = "int"
-		fs.SQLType = "INTEGER"
+		fs.SQLType = columnType(dialect, "int")
 	case "int64":
 		fs.GoType = "int64"
-		fs.SQLType = "INTEGER"
+		fs.SQLType = columnType(dialect, "int64")
 	case "bool", "boolean":
 		fs.GoType = "bool"
-		fs.SQLType = "BOOLEAN"
+		fs.SQLType = columnType(dialect, "bool")
 	case "float", "float64":
 		fs.GoType = "float64"
-		fs.SQLType = "REAL"
+		fs.SQLType = columnType(dialect, "float")
 	case "datetime", "time", "timestamp":
 		fs.GoType = "time.Time"
-		fs.SQLType = "DATETIME"
+		fs.SQLType = columnType(dialect, "datetime")
 	default:
-		// handle decimal(n,m) and varchar(n)
+		// handle associations, decimal(n,m) and varchar(n)
 		low := strings.ToLower(base)
-		if strings.HasPrefix(low, "decimal") || strings.HasPrefix(low, "numeric") {
+		if low == "belongs_to" || strings.HasPrefix(low, "belongs_to:") {
+			// belongs_to:<model> or belongs_to:self
+			target := strings.TrimSpace(strings.TrimPrefix(base[len("belongs_to"):], ":"))
+			if target == "" {
+				return fs, fmt.Errorf("generator: field %q: belongs_to needs a target model (eg. belongs_to:user or belongs_to:self)", name)
+			}
+			if !strings.EqualFold(target, "self") {
+				if err := ValidateIdentifier("model", target); err != nil {
+					return fs, fmt.Errorf("generator: field %q: %w", name, err)
+				}
+			}
+			fs.Association = "belongs_to"
+			fs.AssocName = Title(name)
+			fs.Name = name + "_id"
+			fs.GoName = fs.AssocName + "ID"
+			fs.GoType = "int64"
+			fs.SQLType = columnType(dialect, "int64")
+			if strings.EqualFold(target, "self") {
+				// a self reference must be nullable so root rows can exist
+				fs.SelfRef = true
+				fs.Nullable = true
+			} else {
+				fs.Target = Title(target)
+				fs.References = TableName(target) + ".id"
+			}
+		} else if strings.HasPrefix(low, "decimal") || strings.HasPrefix(low, "numeric") {
 			fs.GoType = "float64"
 			// parse precision/scale if present: decimal(10,2)
 			l := strings.Index(base, "(")
@@ -162,7 +338,7 @@ func ParseFieldSpec(input string) (FieldSpec, error) {
 		} else {
 			// default
 			fs.GoType = "string"
-			fs.SQLType = "TEXT"
+			fs.SQLType = columnType(dialect, "string")
 		}
 	}
 
@@ -180,8 +356,15 @@ func ParseFieldSpec(input string) (FieldSpec, error) {
 			} else if strings.HasPrefix(tok, "default=") {
 				v := strings.TrimPrefix(tok, "default=")
 				fs.Default = &v
+			} else if strings.HasPrefix(tok, "json=") {
+				fs.JSONName = strings.TrimSpace(strings.TrimPrefix(tok, "json="))
 			} else if strings.HasPrefix(tok, "ref=") || strings.HasPrefix(tok, "references=") {
 				v := strings.SplitN(tok, "=", 2)[1]
+				for _, part := range strings.Split(v, ".") {
+					if err := ValidateIdentifier("reference", part); err != nil {
+						return fs, fmt.Errorf("generator: field %q: %w", name, err)
+					}
+				}
 				fs.References = v
 			}
 		}
@@ -208,6 +391,60 @@ func ParseFieldSpec(input string) (FieldSpec, error) {
 	return fs, nil
 }
 
+// ParseColumnList splits a comma-separated column list such as
+// "user_id, slug" into trimmed, non-empty column names.
+func ParseColumnList(s string) []string {
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// ImportPath returns the Go import path of dir by locating the enclosing
+// go.mod and joining its module path with dir's relative location.
+func ImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; {
+		b, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			mod := modulePath(b)
+			if mod == "" {
+				return "", fmt.Errorf("generator: no module directive in %s", filepath.Join(root, "go.mod"))
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return mod, nil
+			}
+			return mod + "/" + filepath.ToSlash(rel), nil
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", fmt.Errorf("generator: no go.mod found in or above %s", abs)
+		}
+		root = parent
+	}
+}
+
+// modulePath returns the module path declared in go.mod contents.
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
+
 // Title returns a Unicode-aware title-cased string using golang.org/x/text.
 // It replaces the deprecated strings.Title usage and handles Unicode word boundaries.
 func Title(s string) string {
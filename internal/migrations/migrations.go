@@ -6,8 +6,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 // MigrationRunner runs timestamped SQL migrations stored in a directory.
@@ -17,11 +19,51 @@ import (
 //	20260108120000_create_users.down.sql
 //
 // ApplyAll executes all .up.sql files in ascending timestamp order.
-type MigrationRunner struct{}
+type MigrationRunner struct {
+	// TableName is the table used to track applied migrations. It defaults
+	// to DefaultTableName; set it when several apps share a database.
+	TableName string
+	// Schema, if set, qualifies TableName (eg. "app" gives
+	// app.flow_migrations). It is intended for Postgres; the schema must
+	// already exist.
+	Schema string
+	// OnApply, if set, is called after each up migration is executed and
+	// recorded, with its base name and execution time.
+	OnApply func(name string, dur time.Duration)
+	// OnRollback, if set, is called after a down migration is executed and
+	// its record removed.
+	OnRollback func(name string)
+}
+
+// DefaultTableName is the migrations tracking table used when
+// MigrationRunner.TableName is empty.
+const DefaultTableName = "flow_migrations"
+
+// identRe matches identifiers accepted for TableName and Schema. They are
+// interpolated into SQL, so anything else is rejected.
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// table returns the validated, optionally schema-qualified tracking table.
+func (m *MigrationRunner) table() (string, error) {
+	name := m.TableName
+	if name == "" {
+		name = DefaultTableName
+	}
+	if !identRe.MatchString(name) {
+		return "", fmt.Errorf("migrations: invalid table name %q", name)
+	}
+	if m.Schema == "" {
+		return name, nil
+	}
+	if !identRe.MatchString(m.Schema) {
+		return "", fmt.Errorf("migrations: invalid schema name %q", m.Schema)
+	}
+	return m.Schema + "." + name, nil
+}
 
 // ApplyAll applies all up migrations found in dir using the provided db.
-// This version tracks applied migrations in a `flow_migrations` table so
-// repeated runs are idempotent.
+// Applied migrations are tracked in TableName (flow_migrations by default)
+// so repeated runs are idempotent.
 func (m *MigrationRunner) ApplyAll(dir string, db *sql.DB) error {
 	// ensure migrations table exists
 	if err := m.ensureTable(db); err != nil {
@@ -43,12 +85,16 @@ func (m *MigrationRunner) ApplyAll(dir string, db *sql.DB) error {
 			// skip already applied
 			continue
 		}
+		start := time.Now()
 		if err := m.execFile(db, p); err != nil {
 			return fmt.Errorf("apply %s: %w", filepath.Base(p), err)
 		}
 		if err := m.markApplied(db, base); err != nil {
 			return fmt.Errorf("mark applied %s: %w", base, err)
 		}
+		if m.OnApply != nil {
+			m.OnApply(base, time.Since(start))
+		}
 	}
 	return nil
 }
@@ -60,9 +106,14 @@ func (m *MigrationRunner) RollbackLast(dir string, db *sql.DB) error {
 		return err
 	}
 
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+
 	// find last applied migration
 	var base string
-	err := db.QueryRow("SELECT name FROM flow_migrations ORDER BY applied_at DESC LIMIT 1").Scan(&base)
+	err = db.QueryRow("SELECT name FROM " + table + " ORDER BY applied_at DESC LIMIT 1").Scan(&base)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("no applied migrations found in %s", dir)
@@ -81,6 +132,9 @@ func (m *MigrationRunner) RollbackLast(dir string, db *sql.DB) error {
 	if err := m.unmarkApplied(db, base); err != nil {
 		return fmt.Errorf("unmark applied %s: %w", base, err)
 	}
+	if m.OnRollback != nil {
+		m.OnRollback(base)
+	}
 	return nil
 }
 
@@ -136,7 +190,12 @@ func (m *MigrationRunner) ApplySingle(path string, db *sql.DB) error {
 	if info.IsDir() {
 		return fmt.Errorf("path is a directory: %s", path)
 	}
+	// validate the tracking table before running any SQL
+	if _, err := m.table(); err != nil {
+		return err
+	}
 	// execute and mark applied if it's an up migration
+	start := time.Now()
 	if err := m.execFile(db, path); err != nil {
 		return err
 	}
@@ -148,6 +207,9 @@ func (m *MigrationRunner) ApplySingle(path string, db *sql.DB) error {
 		if err := m.markApplied(db, base); err != nil {
 			return err
 		}
+		if m.OnApply != nil {
+			m.OnApply(base, time.Since(start))
+		}
 	}
 	return nil
 }
@@ -176,7 +238,11 @@ func (m *MigrationRunner) ListMigrations(dir string) ([]string, error) {
 
 // ensureTable creates the migrations tracking table if it does not exist.
 func (m *MigrationRunner) ensureTable(db *sql.DB) error {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS flow_migrations (
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
         name TEXT PRIMARY KEY,
         applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );`)
@@ -185,8 +251,12 @@ func (m *MigrationRunner) ensureTable(db *sql.DB) error {
 
 // isApplied checks if a migration (by base name) is already applied.
 func (m *MigrationRunner) isApplied(db *sql.DB, base string) (bool, error) {
+	table, err := m.table()
+	if err != nil {
+		return false, err
+	}
 	var cnt int
-	err := db.QueryRow("SELECT count(1) FROM flow_migrations WHERE name = ?", base).Scan(&cnt)
+	err = db.QueryRow("SELECT count(1) FROM "+table+" WHERE name = ?", base).Scan(&cnt)
 	if err != nil {
 		return false, err
 	}
@@ -195,13 +265,21 @@ func (m *MigrationRunner) isApplied(db *sql.DB, base string) (bool, error) {
 
 // markApplied records a migration as applied.
 func (m *MigrationRunner) markApplied(db *sql.DB, base string) error {
-	_, err := db.Exec("INSERT INTO flow_migrations(name) VALUES (?)", base)
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO "+table+"(name) VALUES (?)", base)
 	return err
 }
 
 // unmarkApplied removes a migration record (used on rollback).
 func (m *MigrationRunner) unmarkApplied(db *sql.DB, base string) error {
-	_, err := db.Exec("DELETE FROM flow_migrations WHERE name = ?", base)
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM "+table+" WHERE name = ?", base)
 	return err
 }
 
@@ -210,7 +288,11 @@ func (m *MigrationRunner) AppliedMigrations(db *sql.DB) ([]string, error) {
 	if err := m.ensureTable(db); err != nil {
 		return nil, err
 	}
-	rows, err := db.Query("SELECT name FROM flow_migrations ORDER BY applied_at ASC")
+	table, err := m.table()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT name FROM " + table + " ORDER BY applied_at ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -226,11 +308,98 @@ func (m *MigrationRunner) AppliedMigrations(db *sql.DB) ([]string, error) {
 	return out, rows.Err()
 }
 
+// AppliedMigration is an applied migration and when it was recorded.
+type AppliedMigration struct {
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Status lists applied migrations, in applied order, and pending ones, in
+// timestamp order. It marshals to {"applied":[...],"pending":[...]}.
+type Status struct {
+	Applied []AppliedMigration `json:"applied"`
+	Pending []string           `json:"pending"`
+}
+
+// Status reports which migrations in dir are applied (with their applied_at
+// timestamps) and which are pending.
+func (m *MigrationRunner) Status(dir string, db *sql.DB) (Status, error) {
+	st := Status{Applied: []AppliedMigration{}, Pending: []string{}}
+	if err := m.ensureTable(db); err != nil {
+		return st, err
+	}
+	table, err := m.table()
+	if err != nil {
+		return st, err
+	}
+	rows, err := db.Query("SELECT name, applied_at FROM " + table + " ORDER BY applied_at ASC")
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a AppliedMigration
+		var at interface{}
+		if err := rows.Scan(&a.Name, &at); err != nil {
+			return st, err
+		}
+		a.AppliedAt = parseAppliedAt(at)
+		st.Applied = append(st.Applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+	pending, err := m.PendingMigrations(dir, db)
+	if err != nil {
+		return st, err
+	}
+	st.Pending = append(st.Pending, pending...)
+	return st, nil
+}
+
+// appliedAtLayouts are the textual timestamp formats drivers return for
+// applied_at when they don't decode it to time.Time themselves.
+var appliedAtLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// parseAppliedAt converts a scanned applied_at value to a time, returning
+// the zero time for values it does not recognize.
+func parseAppliedAt(v interface{}) time.Time {
+	var s string
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case []byte:
+		s = string(t)
+	case string:
+		s = t
+	default:
+		return time.Time{}
+	}
+	for _, layout := range appliedAtLayouts {
+		if at, err := time.Parse(layout, s); err == nil {
+			return at
+		}
+	}
+	return time.Time{}
+}
+
 // PendingMigrations returns the list of up migration base names that are not yet applied.
 func (m *MigrationRunner) PendingMigrations(dir string, db *sql.DB) ([]string, error) {
 	if err := m.ensureTable(db); err != nil {
 		return nil, err
 	}
+	return m.UnappliedMigrations(dir, db)
+}
+
+// UnappliedMigrations is PendingMigrations without creating the tracking
+// table, for read-only callers such as readiness probes: a missing table
+// is reported as an error.
+func (m *MigrationRunner) UnappliedMigrations(dir string, db *sql.DB) ([]string, error) {
 	ups, err := m.collect(dir, ".up.sql")
 	if err != nil {
 		return nil, err
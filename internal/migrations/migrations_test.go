@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -89,3 +90,122 @@ func TestApplyAndRollbackSQLite(t *testing.T) {
 		t.Fatalf("expected 0 applied migrations after rollback, got %d", mcnt)
 	}
 }
+
+func TestApplyAllInvokesCallbacks(t *testing.T) {
+	td := t.TempDir()
+	names := []string{"20260101000000_create_a", "20260102000000_create_b"}
+	for _, n := range names {
+		tbl := n[len(n)-1:]
+		if err := os.WriteFile(filepath.Join(td, n+".up.sql"), []byte("CREATE TABLE t_"+tbl+" (id INTEGER);"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(td, n+".down.sql"), []byte("DROP TABLE t_"+tbl+";"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(td, "cb.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	var applied, rolledBack []string
+	runner := &MigrationRunner{
+		OnApply: func(name string, dur time.Duration) {
+			if dur < 0 {
+				t.Errorf("negative duration for %s", name)
+			}
+			applied = append(applied, name)
+		},
+		OnRollback: func(name string) { rolledBack = append(rolledBack, name) },
+	}
+
+	// pre-apply the first migration so only the second is pending
+	if err := (&MigrationRunner{}).ApplySingle(filepath.Join(td, names[0]+".up.sql"), db); err != nil {
+		t.Fatalf("apply single: %v", err)
+	}
+	if err := runner.ApplyAll(td, db); err != nil {
+		t.Fatalf("apply all: %v", err)
+	}
+	if len(applied) != 1 || applied[0] != names[1] {
+		t.Fatalf("expected OnApply once for %s, got %v", names[1], applied)
+	}
+
+	if err := runner.RollbackLast(td, db); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(rolledBack) != 1 {
+		t.Fatalf("expected OnRollback once, got %v", rolledBack)
+	}
+}
+
+func TestCustomTableName(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "20260101000000_create_a.up.sql"), []byte("CREATE TABLE a (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(td, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	runner := &MigrationRunner{TableName: "blog_migrations"}
+	if err := runner.ApplyAll(td, db); err != nil {
+		t.Fatalf("apply all: %v", err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM blog_migrations").Scan(&name); err != nil {
+		t.Fatalf("query blog_migrations: %v", err)
+	}
+	if name != "20260101000000_create_a" {
+		t.Fatalf("unexpected migration record %q", name)
+	}
+	var cnt int
+	if err := db.QueryRow("SELECT count(name) FROM sqlite_master WHERE type='table' AND name='flow_migrations'").Scan(&cnt); err != nil {
+		t.Fatalf("query sqlite_master: %v", err)
+	}
+	if cnt != 0 {
+		t.Fatalf("default tracking table should not be created")
+	}
+
+	bad := &MigrationRunner{TableName: "x; DROP TABLE a"}
+	if err := bad.ApplyAll(td, db); err == nil {
+		t.Fatalf("expected error for invalid table name")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	td := t.TempDir()
+	for name, content := range map[string]string{
+		"20260101000000_create_a.up.sql": "CREATE TABLE a (id INTEGER);",
+		"20260102000000_create_b.up.sql": "CREATE TABLE b (id INTEGER);",
+	} {
+		if err := os.WriteFile(filepath.Join(td, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(td, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	runner := &MigrationRunner{}
+	if err := runner.ApplySingle(filepath.Join(td, "20260101000000_create_a.up.sql"), db); err != nil {
+		t.Fatalf("apply single: %v", err)
+	}
+	st, err := runner.Status(td, db)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(st.Applied) != 1 || st.Applied[0].Name != "20260101000000_create_a" {
+		t.Fatalf("unexpected applied: %+v", st.Applied)
+	}
+	if st.Applied[0].AppliedAt.IsZero() {
+		t.Fatalf("expected applied_at to be set")
+	}
+	if len(st.Pending) != 1 || st.Pending[0] != "20260102000000_create_b" {
+		t.Fatalf("unexpected pending: %v", st.Pending)
+	}
+}
@@ -22,7 +22,7 @@ func Connect(dsn string) (*BunAdapter, error) {
 	// use database/sql for driver registration (caller supplies DSN for sqlite)
 	sqdb, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("open sql: %w", err)
+		return nil, fmt.Errorf("open sql %s: %w", RedactDSN(dsn), RedactError(err, dsn))
 	}
 
 	db := bun.NewDB(sqdb, sqlitedialect.New())
@@ -16,8 +16,12 @@ package router
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -36,6 +40,27 @@ func ParamsFromContext(ctx context.Context) map[string]string {
 	return map[string]string{}
 }
 
+// WithParams returns a copy of ctx carrying params as route parameters,
+// using the same key the router sets during dispatch. It lets handlers be
+// exercised without going through ServeHTTP.
+func WithParams(ctx context.Context, params map[string]string) context.Context {
+	return context.WithValue(ctx, ctxParamsKey{}, params)
+}
+
+// ctxRouteKey is the context key for the matched route's name.
+type ctxRouteKey struct{}
+
+// RouteNameFromContext returns the name of the route matched for the
+// request ("" for unnamed routes or outside the router). It is set before
+// router middleware runs, so a router-scoped logger can report it.
+func RouteNameFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(ctxRouteKey{}).(string)
+	return name
+}
+
 // Param is a convenience helper to fetch a single path parameter by name.
 // It returns an empty string when not present.
 func Param(r *http.Request, name string) string {
@@ -60,12 +85,19 @@ type ResourceController interface {
 type Middleware func(http.Handler) http.Handler
 
 type route struct {
-	method     string
-	pattern    string
-	segments   []string // pattern split by '/'
-	handler    http.HandlerFunc
-	name       string
-	middleware []Middleware
+	method   string
+	pattern  string
+	segments []string // pattern split by '/', constraints stripped
+	// constraints holds the compiled constraint of each segment, nil for
+	// segments without one.
+	constraints []paramMatcher
+	handler     http.HandlerFunc
+	name        string
+	middleware  []Middleware
+	// exact routes skip trailing-slash normalization: trailingSlash must
+	// match whether the request path ends in '/'.
+	exact         bool
+	trailingSlash bool
 }
 
 // Router is a simple HTTP router that supports path parameters using the
@@ -75,9 +107,55 @@ type Router struct {
 	// NotFound handler can be customized. If nil, http.NotFound is used.
 	NotFound http.Handler
 	// MethodNotAllowed handler called when a path matches but method doesn't.
+	// The Allow header is already set when it runs.
 	MethodNotAllowed http.Handler
+	// AutoOptions answers OPTIONS requests for known paths (without an
+	// explicit OPTIONS route) with 204 and an Allow header listing the
+	// registered methods, eg. for CORS preflights.
+	AutoOptions bool
+	// RedirectTrailingSlash redirects "/users/" to "/users" when the
+	// trimmed path matches a route, instead of serving it directly. GET and
+	// HEAD get 301; other methods get 308 so the method and body are kept.
+	RedirectTrailingSlash bool
+	// basePath is stripped from incoming paths before matching and
+	// prepended by URL. Empty means routes are served from the root.
+	basePath string
+	// middleware registered with Use wraps every matched route.
+	middleware []Middleware
+	// pluralize makes Resources pluralize the last segment of its base.
+	pluralize bool
+}
+
+// Use appends router-wide middleware. It applies to every route, including
+// routes registered before the call and those added by Resources, and runs
+// outside per-route middleware (first registered is outer-most). When the
+// router is served by a Flow App, App middleware runs outside router
+// middleware.
+func (r *Router) Use(mws ...Middleware) {
+	r.middleware = append(r.middleware, mws...)
+}
+
+// SetBasePath configures a global prefix (eg. "/app") for apps deployed
+// under a sub-path. Incoming requests must start with the prefix (otherwise
+// they are not found); it is stripped before matching and prepended to
+// paths generated by URL. An empty prefix or "/" disables it.
+func (r *Router) SetBasePath(prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		r.basePath = ""
+		return
+	}
+	r.basePath = "/" + prefix
 }
 
+// SetPluralize controls whether Resources pluralizes its base, so
+// Resources("user", c) serves /users. It is off by default and the base is
+// used as given.
+func (r *Router) SetPluralize(on bool) { r.pluralize = on }
+
+// BasePath returns the configured global prefix ("" when unset).
+func (r *Router) BasePath() string { return r.basePath }
+
 // New creates an empty Router.
 func New() *Router {
 	return &Router{}
@@ -85,14 +163,87 @@ func New() *Router {
 
 // Handle registers a handler for method and pattern.
 // Pattern must start with '/'. Parameter segments start with ':' and match a
-// single path segment.
+// single path segment. A parameter may carry a constraint after '|', either
+// a shorthand (int, alpha, uuid, slug) or a regular expression matched
+// against the whole segment (so it can't contain '/'), eg. "/users/:id|int"
+// or "/tags/:name|[a-z-]+". Requests whose segment fails the constraint don't
+// match the route, so a later route (or NotFound) handles them. Invalid
+// expressions and a method+pattern that is already registered panic; use
+// TryHandle to get an error instead. A final "*name" segment matches the
+// rest of the path, eg. "/files/*path" gives path "a/b.txt" for
+// "/files/a/b.txt".
 func (r *Router) Handle(method, pattern string, h http.HandlerFunc) {
 	if !strings.HasPrefix(pattern, "/") {
 		panic("router: pattern must begin with '/'")
 	}
-	segs := splitPath(pattern)
-	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, handler: h}
+	segs, cons := mustCompilePattern(pattern)
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h}
+	r.mustAdd(rt)
+}
+
+// TryHandle is like Handle but returns an error instead of panicking when
+// the pattern is invalid or the method and pattern are already registered.
+func (r *Router) TryHandle(method, pattern string, h http.HandlerFunc) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("router: pattern must begin with '/'")
+	}
+	segs, cons, err := compilePattern(pattern)
+	if err != nil {
+		return err
+	}
+	return r.add(&route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h})
+}
+
+// add appends rt unless a route with the same method and pattern shape
+// exists: matching stops at the first hit, so the second would never run.
+func (r *Router) add(rt *route) error {
+	shape := patternShape(rt.pattern)
+	for _, existing := range r.routes {
+		if existing.method == rt.method && existing.exact == rt.exact &&
+			existing.trailingSlash == rt.trailingSlash &&
+			patternShape(existing.pattern) == shape {
+			return fmt.Errorf("router: duplicate route %s %s", rt.method, rt.pattern)
+		}
+	}
 	r.routes = append(r.routes, rt)
+	return nil
+}
+
+// patternShape returns pattern with parameter and wildcard names dropped
+// but constraints kept, so "/users/:id" and "/users/:uid" compare equal
+// while "/users/:id|int" differs from both.
+func patternShape(pattern string) string {
+	segs := splitPath(pattern)
+	for i, seg := range segs {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			_, expr, _ := strings.Cut(seg, "|")
+			segs[i] = ":|" + expr
+		case strings.HasPrefix(seg, "*"):
+			segs[i] = "*"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// mustAdd is add for the panicking Handle variants.
+func (r *Router) mustAdd(rt *route) {
+	if err := r.add(rt); err != nil {
+		panic(err.Error())
+	}
+}
+
+// HandleExact registers a route that opts out of trailing-slash
+// normalization, so "/dir/" and "/dir" are distinct: the route only matches
+// requests whose path ends in '/' exactly when pattern does.
+func (r *Router) HandleExact(method, pattern string, h http.HandlerFunc) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern must begin with '/'")
+	}
+	segs, cons := mustCompilePattern(pattern)
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h,
+		exact: true, trailingSlash: hasTrailingSlash(pattern)}
+	r.mustAdd(rt)
 }
 
 // HandleWith allows attaching per-route middleware for this route.
@@ -100,9 +251,9 @@ func (r *Router) HandleWith(method, pattern string, h http.HandlerFunc, mws ...M
 	if !strings.HasPrefix(pattern, "/") {
 		panic("router: pattern must begin with '/'")
 	}
-	segs := splitPath(pattern)
-	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, handler: h, middleware: mws}
-	r.routes = append(r.routes, rt)
+	segs, cons := mustCompilePattern(pattern)
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h, middleware: mws}
+	r.mustAdd(rt)
 }
 
 // HandleNamed registers a named route. If the name is already in use the function panics.
@@ -119,9 +270,9 @@ func (r *Router) HandleNamed(name, method, pattern string, h http.HandlerFunc) {
 	if !strings.HasPrefix(pattern, "/") {
 		panic("router: pattern must begin with '/'")
 	}
-	segs := splitPath(pattern)
-	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, handler: h, name: name}
-	r.routes = append(r.routes, rt)
+	segs, cons := mustCompilePattern(pattern)
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h, name: name}
+	r.mustAdd(rt)
 }
 
 // HandleNamedWith registers a named route with per-route middleware.
@@ -137,9 +288,9 @@ func (r *Router) HandleNamedWith(name, method, pattern string, h http.HandlerFun
 	if !strings.HasPrefix(pattern, "/") {
 		panic("router: pattern must begin with '/'")
 	}
-	segs := splitPath(pattern)
-	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, handler: h, name: name, middleware: mws}
-	r.routes = append(r.routes, rt)
+	segs, cons := mustCompilePattern(pattern)
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: segs, constraints: cons, handler: h, name: name, middleware: mws}
+	r.mustAdd(rt)
 }
 
 // Convenience sugar: GetNamed, PostNamed, PutNamed, PatchNamed, DeleteNamed
@@ -191,6 +342,10 @@ func (r *Router) Resources(base string, c ResourceController) error {
 		return fmt.Errorf("router: Resources base cannot be empty")
 	}
 	base = strings.Trim(base, "/")
+	if r.pluralize {
+		i := strings.LastIndex(base, "/") + 1
+		base = base[:i] + Pluralize(base[i:])
+	}
 
 	// index, new, create
 	r.GetNamed(fmt.Sprintf("%s_index", base), fmt.Sprintf("/%s", base), c.Index)
@@ -208,36 +363,241 @@ func (r *Router) Resources(base string, c ResourceController) error {
 	return nil
 }
 
+// Resource wires a ResourceController to the routes of a singleton
+// resource, one per client (eg. "profile"), so no :id segment is used:
+//
+//	GET    /profile/new   New      (profile_new)
+//	POST   /profile       Create   (profile_create)
+//	GET    /profile       Show     (profile_show)
+//	GET    /profile/edit  Edit     (profile_edit)
+//	PUT    /profile       Update   (profile_update)
+//	PATCH  /profile       Update   (profile_patch)
+//	DELETE /profile       Destroy  (profile_destroy)
+//
+// Index is not routed. The base is never pluralized.
+func (r *Router) Resource(base string, c ResourceController) error {
+	if base == "" {
+		return fmt.Errorf("router: Resource base cannot be empty")
+	}
+	base = strings.Trim(base, "/")
+	path := "/" + base
+
+	r.GetNamed(fmt.Sprintf("%s_new", base), path+"/new", c.New)
+	r.PostNamed(fmt.Sprintf("%s_create", base), path, c.Create)
+	r.GetNamed(fmt.Sprintf("%s_show", base), path, c.Show)
+	r.GetNamed(fmt.Sprintf("%s_edit", base), path+"/edit", c.Edit)
+	r.PutNamed(fmt.Sprintf("%s_update", base), path, c.Update)
+	r.PatchNamed(fmt.Sprintf("%s_patch", base), path, c.Update)
+	r.DeleteNamed(fmt.Sprintf("%s_destroy", base), path, c.Destroy)
+
+	return nil
+}
+
+// Pluralize returns a naive English plural of word: words already ending
+// in "s" are kept, "ch", "sh", "x" and "z" take "es", a consonant followed
+// by "y" becomes "ies", and anything else takes "s".
+func Pluralize(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case word == "", strings.HasSuffix(lower, "s"):
+		return word
+	case strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"),
+		strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"):
+		return word + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// StaticConfig customises StaticWith. The zero value behaves like Static.
+type StaticConfig struct {
+	// Index is the file served for a directory path (eg. "index.html").
+	// Directories are never listed: without an Index, or when the
+	// directory has none, they go to NotFound.
+	Index string
+	// Authorize, when set, is called with the request and the requested
+	// path relative to dir ("admin/report.pdf"); returning false answers
+	// 403 Forbidden without touching the file.
+	Authorize func(req *http.Request, name string) bool
+	// Headers adds response headers per file extension, eg.
+	// {".js": {"Cache-Control": {"public, max-age=31536000"}}}. Extensions
+	// include the dot and match case-insensitively.
+	Headers map[string]http.Header
+}
+
+// Static serves the files under dir at urlPrefix, eg. Static("/static",
+// "public") maps GET /static/css/app.css to public/css/app.css with a
+// Content-Type derived from the extension. Missing files and directories
+// go to NotFound; ".." paths are rejected before matching (see CleanPath)
+// and http.Dir refuses them too.
+func (r *Router) Static(urlPrefix, dir string) {
+	r.StaticWith(urlPrefix, dir, StaticConfig{})
+}
+
+// StaticWith is Static with an index file, per-request authorization and
+// per-extension headers configured by cfg.
+func (r *Router) StaticWith(urlPrefix, dir string, cfg StaticConfig) {
+	pattern := "/" + strings.Trim(urlPrefix, "/") + "/*filepath"
+	if strings.Trim(urlPrefix, "/") == "" {
+		pattern = "/*filepath"
+	}
+	headers := make(map[string]http.Header, len(cfg.Headers))
+	for ext, h := range cfg.Headers {
+		headers[strings.ToLower(ext)] = h
+	}
+	root := http.Dir(dir)
+	h := func(w http.ResponseWriter, req *http.Request) {
+		name := Param(req, "filepath")
+		if cfg.Authorize != nil && !cfg.Authorize(req, name) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		f, fi, ok := openStatic(root, "/"+name)
+		if ok && fi.IsDir() {
+			f.Close()
+			ok = false
+			if cfg.Index != "" {
+				f, fi, ok = openStatic(root, path.Join("/", name, cfg.Index))
+				if ok && fi.IsDir() {
+					f.Close()
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			r.notFound(w, req)
+			return
+		}
+		defer f.Close()
+		for k, vs := range headers[strings.ToLower(path.Ext(fi.Name()))] {
+			for _, v := range vs {
+				w.Header().Add(k, v)
+			}
+		}
+		http.ServeContent(w, req, fi.Name(), fi.ModTime(), f)
+	}
+	r.Get(pattern, h)
+	r.Handle(http.MethodHead, pattern, h)
+}
+
+// openStatic opens name in root, reporting whether it exists.
+func openStatic(root http.FileSystem, name string) (http.File, fs.FileInfo, bool) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, false
+	}
+	return f, fi, true
+}
+
+// anyMethod is the route method that matches every request method.
+const anyMethod = "*"
+
+// mountParam is the wildcard param Mount uses to capture the sub-path.
+const mountParam = "__mount"
+
+// Mount delegates every request for prefix, or a path below it, to sub
+// with the prefix stripped, eg. Mount("/api", apiRouter) serves
+// /api/widgets/5 as /widgets/5 in apiRouter. The prefix may contain params
+// ("/orgs/:org"); they stay available to sub's handlers through Param when
+// sub is a Router. Mounts match any method.
+func (r *Router) Mount(prefix string, sub http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	h := func(w http.ResponseWriter, req *http.Request) {
+		params := ParamsFromContext(req.Context())
+		parent := make(map[string]string, len(params))
+		for k, v := range params {
+			if k != mountParam {
+				parent[k] = v
+			}
+		}
+		u := *req.URL
+		u.Path = "/" + params[mountParam]
+		u.RawPath = ""
+		req = req.WithContext(context.WithValue(req.Context(), ctxParamsKey{}, parent))
+		req.URL = &u
+		sub.ServeHTTP(w, req)
+	}
+	r.Handle(anyMethod, prefix, h)
+	r.Handle(anyMethod, strings.TrimSuffix(prefix, "/")+"/*"+mountParam, h)
+}
+
 // ServeHTTP implements http.Handler. It finds the first matching route
 // (in registration order), injects params into the request context, and
 // invokes the handler. If no route matches, NotFound is called. If a path
-// matches but the method does not, MethodNotAllowed is called.
+// matches but the method does not, the Allow header is set and
+// MethodNotAllowed is called (or, with AutoOptions, OPTIONS gets a 204).
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	path := normalizePath(req.URL.Path)
-	var methodMismatch bool
+	path, ok := CleanPath(req.URL.Path)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	// cleaned keeps the base path for redirects
+	cleaned := path
+	// methods registered for the path, reported in Allow on 405/OPTIONS
+	var allowed []string
+
+	if r.basePath != "" {
+		if path != r.basePath && !strings.HasPrefix(path, r.basePath+"/") {
+			r.notFound(w, req)
+			return
+		}
+		path = normalizePath("/" + strings.TrimPrefix(path, r.basePath))
+	}
 
 	for _, rt := range r.routes {
-		ok, params := matchRoute(rt.segments, path)
+		ok, params := matchRoute(rt.segments, rt.constraints, path)
 		if !ok {
 			continue
 		}
-		if rt.method != req.Method {
-			methodMismatch = true
+		if rt.exact {
+			if rt.trailingSlash != hasTrailingSlash(req.URL.Path) {
+				continue
+			}
+		} else if r.RedirectTrailingSlash && hasTrailingSlash(req.URL.Path) {
+			r.redirectTrimmed(w, req, cleaned)
+			return
+		}
+		if rt.method != req.Method && rt.method != anyMethod {
+			allowed = append(allowed, rt.method)
 			continue
 		}
 
-		// inject params into context
+		// inject params into context, keeping those of an outer router
+		// that mounted this one
+		for k, v := range ParamsFromContext(req.Context()) {
+			if _, ok := params[k]; !ok {
+				params[k] = v
+			}
+		}
 		ctx := context.WithValue(req.Context(), ctxParamsKey{}, params)
+		if rt.name != "" {
+			ctx = context.WithValue(ctx, ctxRouteKey{}, rt.name)
+		}
 		// build handler with route middleware (first registered is outer-most)
 		var final http.Handler = http.HandlerFunc(rt.handler)
 		for i := len(rt.middleware) - 1; i >= 0; i-- {
 			final = rt.middleware[i](final)
 		}
+		for i := len(r.middleware) - 1; i >= 0; i-- {
+			final = r.middleware[i](final)
+		}
 		final.ServeHTTP(w, req.WithContext(ctx))
 		return
 	}
 
-	if methodMismatch {
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", allowHeader(allowed))
+		if req.Method == http.MethodOptions && r.AutoOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 		if r.MethodNotAllowed != nil {
 			r.MethodNotAllowed.ServeHTTP(w, req)
 			return
@@ -246,6 +606,44 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.notFound(w, req)
+}
+
+// hasTrailingSlash reports whether p ends in '/' and is not the root.
+func hasTrailingSlash(p string) bool {
+	return len(p) > 1 && strings.HasSuffix(p, "/")
+}
+
+// redirectTrimmed redirects req to cleaned, its CleanPath-normalized path
+// (which has no trailing slash), keeping the query string. Building the
+// target from the raw path would turn "//evil.com/" into a
+// protocol-relative redirect to another host.
+func (r *Router) redirectTrimmed(w http.ResponseWriter, req *http.Request, cleaned string) {
+	target := "/" + strings.TrimLeft(cleaned, "/")
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, req, target, code)
+}
+
+// allowHeader formats methods as a sorted, de-duplicated Allow value.
+func allowHeader(methods []string) string {
+	sort.Strings(methods)
+	out := methods[:0]
+	for i, m := range methods {
+		if i == 0 || m != methods[i-1] {
+			out = append(out, m)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// notFound invokes the NotFound handler or http.NotFound.
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
 	if r.NotFound != nil {
 		r.NotFound.ServeHTTP(w, req)
 		return
@@ -264,17 +662,169 @@ func splitPath(p string) []string {
 	return parts
 }
 
+// paramMatcher reports whether a path segment satisfies a constraint.
+type paramMatcher func(string) bool
+
+// paramShorthands are the named constraints accepted after '|'. They are
+// plain character checks; anything else is compiled as a regular
+// expression.
+var paramShorthands = map[string]paramMatcher{
+	"int":   isDigits,
+	"alpha": isAlnum,
+	"uuid":  isUUID,
+	"slug":  isSlug,
+}
+
+// compilePattern splits pattern like splitPath and resolves parameter
+// constraints (":id|int"), returning the segments with the constraints
+// stripped and a matcher per segment (nil when the pattern has none).
+func compilePattern(pattern string) ([]string, []paramMatcher, error) {
+	segs := splitPath(pattern)
+	var cons []paramMatcher
+	for i, s := range segs {
+		if !strings.HasPrefix(s, ":") {
+			continue
+		}
+		name, expr, ok := strings.Cut(s, "|")
+		if !ok {
+			continue
+		}
+		m, known := paramShorthands[expr]
+		if !known {
+			re, err := regexp.Compile(`^(?:` + expr + `)$`)
+			if err != nil {
+				return nil, nil, fmt.Errorf("router: invalid constraint for %s in %s: %w", name, pattern, err)
+			}
+			m = re.MatchString
+		}
+		if cons == nil {
+			cons = make([]paramMatcher, len(segs))
+		}
+		segs[i] = name
+		cons[i] = m
+	}
+	return segs, cons, nil
+}
+
+// mustCompilePattern is compilePattern for the panicking Handle variants.
+func mustCompilePattern(pattern string) ([]string, []paramMatcher) {
+	segs, cons, err := compilePattern(pattern)
+	if err != nil {
+		panic(err.Error())
+	}
+	return segs, cons
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnum reports whether s is a non-empty run of ASCII letters and digits.
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isAlnumByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnumByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isUUID reports whether s has the 8-4-4-4-12 hex layout of a UUID.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isSlug reports whether s is lowercase letters and digits in
+// hyphen-separated words ("hello-world-2").
+func isSlug(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RouteInfo describes a registered route for introspection.
+type RouteInfo struct {
+	Method  string
+	Pattern string
+	Name    string // empty for unnamed routes
+}
+
+// Routes returns the registered routes in registration order (the order
+// they are matched in). Patterns are as registered, without the base path.
+func (r *Router) Routes() []RouteInfo {
+	out := make([]RouteInfo, len(r.routes))
+	for i, rt := range r.routes {
+		out[i] = RouteInfo{Method: rt.method, Pattern: rt.pattern, Name: rt.name}
+	}
+	return out
+}
+
 // URL builds a path for a named route by substituting params into the
 // named route's pattern. Returns an error if the name is unknown or if a
-// required param is missing. Param values are path-escaped.
+// required param is missing. Param values are path-escaped. The base path,
+// if configured, is prepended.
 func (r *Router) URL(name string, params map[string]string) (string, error) {
 	for _, rt := range r.routes {
 		if rt.name == name {
 			if len(rt.segments) == 0 {
+				if r.basePath != "" {
+					return r.basePath, nil
+				}
 				return "/", nil
 			}
 			parts := make([]string, 0, len(rt.segments))
 			for _, s := range rt.segments {
+				if strings.HasPrefix(s, "*") {
+					key := s[1:]
+					v, ok := params[key]
+					if !ok {
+						return "", fmt.Errorf("router: missing param %s for route %s", key, name)
+					}
+					parts = append(parts, strings.Trim(v, "/"))
+					continue
+				}
 				if strings.HasPrefix(s, ":") {
 					key := strings.TrimPrefix(s, ":")
 					v, ok := params[key]
@@ -286,28 +836,63 @@ func (r *Router) URL(name string, params map[string]string) (string, error) {
 				}
 				parts = append(parts, s)
 			}
-			return "/" + strings.Join(parts, "/"), nil
+			return r.basePath + "/" + strings.Join(parts, "/"), nil
 		}
 	}
 	return "", fmt.Errorf("router: unknown route %s", name)
 }
 
-// normalizePath prepares an incoming request path for matching.
-// It removes a trailing slash unless the path is just "/".
-func normalizePath(p string) string {
-	if p == "/" {
-		return p
+// URLWithQuery builds the path of the named route like URL and appends
+// query, encoded and sorted by key (eg. "/users?page=2"). Only the explicit
+// query values are appended; params keys that are not path parameters are
+// ignored rather than turned into query parameters. An empty query adds
+// nothing.
+func (r *Router) URLWithQuery(name string, params map[string]string, query url.Values) (string, error) {
+	p, err := r.URL(name, params)
+	if err != nil {
+		return "", err
 	}
-	p = strings.TrimSuffix(p, "/")
-	if p == "" {
-		return "/"
+	if q := query.Encode(); q != "" {
+		p += "?" + q
+	}
+	return p, nil
+}
+
+// normalizePath prepares an incoming request path for matching: duplicate
+// slashes are collapsed, "." segments dropped and a trailing slash trimmed.
+// Paths containing ".." are returned unchanged; ServeHTTP rejects them via
+// CleanPath before matching.
+func normalizePath(p string) string {
+	if clean, ok := CleanPath(p); ok {
+		return clean
 	}
 	return p
 }
 
+// CleanPath collapses duplicate slashes, drops "." segments and trims a
+// trailing slash, so "/users//7/" and "/users/./7" both become "/users/7".
+// It reports false for paths with a ".." segment: rather than resolving
+// them, which could let a request step outside a prefix checked earlier
+// (a base path, a mount, a static directory), the router rejects them.
+func CleanPath(p string) (string, bool) {
+	segs := strings.Split(p, "/")
+	out := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		switch seg {
+		case "", ".":
+			continue
+		case "..":
+			return "", false
+		}
+		out = append(out, seg)
+	}
+	return "/" + strings.Join(out, "/"), true
+}
+
 // matchRoute attempts to match the candidate path to the route segments.
+// cons holds per-segment constraints (see compilePattern) and may be nil.
 // Returns ok and a map of parameters when matched.
-func matchRoute(segs []string, path string) (bool, map[string]string) {
+func matchRoute(segs []string, cons []paramMatcher, path string) (bool, map[string]string) {
 	// handle root
 	if len(segs) == 0 {
 		return path == "/", map[string]string{}
@@ -318,6 +903,25 @@ func matchRoute(segs []string, path string) (bool, map[string]string) {
 		return false, nil
 	}
 	parts := strings.Split(trimmed, "/")
+
+	// a trailing "*name" segment captures the rest of the path (at least
+	// one segment), slashes included
+	if last := segs[len(segs)-1]; strings.HasPrefix(last, "*") {
+		n := len(segs) - 1
+		if len(parts) <= n {
+			return false, nil
+		}
+		if cons != nil {
+			cons = cons[:n]
+		}
+		ok, params := matchRoute(segs[:n], cons, "/"+strings.Join(parts[:n], "/"))
+		if !ok {
+			return false, nil
+		}
+		params[last[1:]] = strings.Join(parts[n:], "/")
+		return true, params
+	}
+
 	if len(parts) != len(segs) {
 		return false, nil
 	}
@@ -338,6 +942,9 @@ func matchRoute(segs []string, path string) (bool, map[string]string) {
 			if name == "" {
 				return false, nil
 			}
+			if cons != nil && cons[i] != nil && !cons[i](p) {
+				return false, nil
+			}
 			params[name] = p
 			continue
 		}
@@ -347,6 +954,3 @@ func matchRoute(segs []string, path string) (bool, map[string]string) {
 	}
 	return true, params
 }
-
-// TODO: Consider adding support for named route lookup, middleware per-route
-// and wildcard segments ("*path") should the framework require them later.
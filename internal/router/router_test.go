@@ -4,6 +4,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -100,6 +104,69 @@ func TestRouterBasicMatching(t *testing.T) {
 		}
 	})
 
+	t.Run("trailing slash redirect", func(t *testing.T) {
+		r := New()
+		r.RedirectTrailingSlash = true
+		ok := func(w http.ResponseWriter, req *http.Request) {}
+		r.Get("/users", ok)
+		r.Post("/users", ok)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/users/?page=2", nil))
+		if rr.Code != http.StatusMovedPermanently {
+			t.Fatalf("expected 301 for GET /users/, got %d", rr.Code)
+		}
+		if loc := rr.Header().Get("Location"); loc != "/users?page=2" {
+			t.Fatalf("expected Location /users?page=2, got %q", loc)
+		}
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("POST", "/users/", nil))
+		if rr.Code != http.StatusPermanentRedirect {
+			t.Fatalf("expected 308 for POST /users/, got %d", rr.Code)
+		}
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/users", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 for canonical path, got %d", rr.Code)
+		}
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/missing/", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for unknown path, got %d", rr.Code)
+		}
+	})
+
+	t.Run("trailing slash redirect stays on host", func(t *testing.T) {
+		r := New()
+		r.RedirectTrailingSlash = true
+		r.Get("/:page", func(w http.ResponseWriter, req *http.Request) {})
+
+		for path, want := range map[string]string{
+			"//evil.com/":   "/evil.com",
+			"///evil.com/":  "/evil.com",
+			"/./evil.com//": "/evil.com",
+		} {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			if rr.Code != http.StatusMovedPermanently {
+				t.Fatalf("%s: expected 301, got %d", path, rr.Code)
+			}
+			if loc := rr.Header().Get("Location"); loc != want {
+				t.Fatalf("%s: expected Location %q, got %q", path, want, loc)
+			}
+		}
+
+		r.SetBasePath("/app")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/app//about/", nil))
+		if loc := rr.Header().Get("Location"); loc != "/app/about" {
+			t.Fatalf("expected Location /app/about, got %q", loc)
+		}
+	})
+
 	t.Run("multiple params", func(t *testing.T) {
 		r := New()
 		r.Get("/orgs/:org_id/users/:id", func(w http.ResponseWriter, req *http.Request) {
@@ -189,3 +256,555 @@ func TestResourcesRegisterNames(t *testing.T) {
 		t.Fatalf("expected /users/7 got %s", p)
 	}
 }
+
+func TestBasePath(t *testing.T) {
+	r := New()
+	r.SetBasePath("/app/")
+	r.GetNamed("root", "/", func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write([]byte("root")) })
+	r.GetNamed("user_show", "/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(Param(req, "id")))
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/app/users/7", http.StatusOK, "7"},
+		{"/app", http.StatusOK, "root"},
+		{"/app/", http.StatusOK, "root"},
+		{"/users/7", http.StatusNotFound, ""},
+		{"/application/users/7", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s: unexpected body %q", tc.path, rr.Body.String())
+		}
+	}
+
+	p, err := r.URL("user_show", map[string]string{"id": "7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p != "/app/users/7" {
+		t.Fatalf("expected /app/users/7 got %s", p)
+	}
+	if p, _ := r.URL("root", nil); p != "/app" {
+		t.Fatalf("expected /app for root route, got %s", p)
+	}
+}
+
+func TestUseAppliesToAllRoutes(t *testing.T) {
+	r := New()
+	var order []string
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			order = append(order, "router")
+			next.ServeHTTP(w, req)
+		})
+	})
+	routeMW := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			order = append(order, "route")
+			next.ServeHTTP(w, req)
+		})
+	}
+	r.HandleWith("GET", "/ping", func(w http.ResponseWriter, req *http.Request) {
+		order = append(order, "handler")
+	}, routeMW)
+	if err := r.Resources("users", &testCtrl{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+	if got := strings.Join(order, ","); got != "router,route,handler" {
+		t.Fatalf("unexpected order for plain route: %s", got)
+	}
+
+	order = nil
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/users/7", nil))
+	if rr.Body.String() != "s" || len(order) != 1 || order[0] != "router" {
+		t.Fatalf("router middleware not applied to resource route: body=%q order=%v", rr.Body.String(), order)
+	}
+}
+
+func TestSingularResourceRoutes(t *testing.T) {
+	r := New()
+	if err := r.Resource("profile", &testCtrl{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct{ method, path, want string }{
+		{"GET", "/profile", "s"},
+		{"GET", "/profile/new", "n"},
+		{"POST", "/profile", "c"},
+		{"GET", "/profile/edit", "e"},
+		{"PUT", "/profile", "u"},
+		{"PATCH", "/profile", "u"},
+		{"DELETE", "/profile", "d"},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		if rr.Body.String() != tc.want {
+			t.Errorf("%s %s: expected %q got %q (status %d)", tc.method, tc.path, tc.want, rr.Body.String(), rr.Code)
+		}
+	}
+	if p, err := r.URL("profile_edit", nil); err != nil || p != "/profile/edit" {
+		t.Fatalf("expected /profile/edit, got %q, %v", p, err)
+	}
+}
+
+func TestResourcesPluralize(t *testing.T) {
+	r := New()
+	r.SetPluralize(true)
+	if err := r.Resources("user", &testCtrl{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/users/7", nil))
+	if rr.Body.String() != "s" {
+		t.Fatalf("expected pluralized show route, got %q (status %d)", rr.Body.String(), rr.Code)
+	}
+	if p, err := r.URL("users_index", nil); err != nil || p != "/users" {
+		t.Fatalf("expected /users, got %q, %v", p, err)
+	}
+
+	// without pluralization the base is used as-is
+	plain := New()
+	_ = plain.Resources("user", &testCtrl{})
+	rr = httptest.NewRecorder()
+	plain.ServeHTTP(rr, httptest.NewRequest("GET", "/user/7", nil))
+	if rr.Body.String() != "s" {
+		t.Fatalf("expected verbatim base, got %q", rr.Body.String())
+	}
+
+	for in, want := range map[string]string{"post": "posts", "category": "categories", "day": "days", "box": "boxes", "match": "matches", "news": "news"} {
+		if got := Pluralize(in); got != want {
+			t.Errorf("Pluralize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(Param(req, "id")))
+	})
+	for _, p := range []string{"/users//7", "/users/./7", "//users/7/", "/users/7/."} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", p, nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != "7" {
+			t.Errorf("%s: expected 200 with id 7, got %d %q", p, rr.Code, rr.Body.String())
+		}
+	}
+
+	for _, p := range []string{"/users/../admin", "/users/7/..", "/../users/7"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = p
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected traversal to be rejected with 400, got %d", p, rr.Code)
+		}
+	}
+}
+
+func TestURLWithQuery(t *testing.T) {
+	r := New()
+	if err := r.Resources("users", &testCtrl{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		query  url.Values
+		want   string
+	}{
+		{"users_index", nil, nil, "/users"},
+		{"users_index", nil, url.Values{}, "/users"},
+		{"users_index", nil, url.Values{"page": {"2"}}, "/users?page=2"},
+		{"users_index", nil, url.Values{"tag": {"a", "b"}, "page": {"3"}}, "/users?page=3&tag=a&tag=b"},
+		{"users_index", nil, url.Values{"q": {"a&b c/d?"}}, "/users?q=a%26b+c%2Fd%3F"},
+		// keys that are not path params are not appended implicitly
+		{"users_show", map[string]string{"id": "7", "page": "2"}, nil, "/users/7"},
+	}
+	for _, tc := range cases {
+		got, err := r.URLWithQuery(tc.name, tc.params, tc.query)
+		if err != nil {
+			t.Fatalf("URLWithQuery(%s): %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("URLWithQuery(%s, %v, %v) = %q, want %q", tc.name, tc.params, tc.query, got, tc.want)
+		}
+	}
+	if _, err := r.URLWithQuery("users_show", nil, url.Values{"id": {"7"}}); err == nil {
+		t.Fatalf("expected missing path param error; query values must not fill path params")
+	}
+}
+
+func TestAllowHeaderAndAutoOptions(t *testing.T) {
+	r := New()
+	ok := func(w http.ResponseWriter, req *http.Request) {}
+	r.Post("/items", ok)
+	r.Get("/items", ok)
+	r.Get("/items/:id", ok)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("DELETE", "/items", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Allow"); got != "GET, POST" {
+		t.Fatalf("expected Allow: GET, POST on 405, got %q", got)
+	}
+
+	// without AutoOptions, OPTIONS is just another unregistered method
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/items", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for OPTIONS without AutoOptions, got %d", rr.Code)
+	}
+
+	r.AutoOptions = true
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/items", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for OPTIONS, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Allow"); got != "GET, POST" {
+		t.Fatalf("expected Allow: GET, POST, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for OPTIONS on unknown path, got %d", rr.Code)
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	r := New()
+	echo := func(prefix string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(prefix + ":" + Param(req, "id")))
+		}
+	}
+	r.Get("/users/:id|int", echo("int"))
+	r.Get("/users/:id", echo("any"))
+	r.Get("/tags/:id|[a-z-]+", echo("tag"))
+	r.Get("/orders/:id|uuid", echo("uuid"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", http.StatusOK, "int:42"},
+		{"/users/abc", http.StatusOK, "any:abc"}, // falls through to the next route
+		{"/tags/go-lang", http.StatusOK, "tag:go-lang"},
+		{"/tags/Go1", http.StatusNotFound, ""},
+		{"/orders/123e4567-e89b-12d3-a456-426614174000", http.StatusOK, "uuid:123e4567-e89b-12d3-a456-426614174000"},
+		{"/orders/123", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s: expected %q, got %q", tc.path, tc.body, rr.Body.String())
+		}
+	}
+
+	n := New()
+	n.GetNamed("order_show", "/orders/:id|uuid", echo("uuid"))
+	if u, err := n.URL("order_show", map[string]string{"id": "abc"}); err != nil || u != "/orders/abc" {
+		t.Fatalf("expected /orders/abc from constrained pattern, got %q (%v)", u, err)
+	}
+}
+
+func TestParamShorthands(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/users/:id|int", "/users/42", true},
+		{"/users/:id|int", "/users/abc", false},
+		{"/users/:id|int", "/users/-1", false},
+		{"/pages/:slug|alpha", "/pages/About2", true},
+		{"/pages/:slug|alpha", "/pages/about-us", false},
+		{"/posts/:slug|slug", "/posts/hello-world-2", true},
+		{"/posts/:slug|slug", "/posts/Hello--world", false},
+		{"/items/:uuid|uuid", "/items/123e4567-e89b-12d3-a456-426614174000", true},
+		{"/items/:uuid|uuid", "/items/123e4567e89b12d3a456426614174000", false},
+		{"/items/:uuid|uuid", "/items/123e4567-e89b-12d3-a456-42661417400g", false},
+	}
+	for _, tc := range cases {
+		r := New()
+		r.Get(tc.pattern, func(w http.ResponseWriter, req *http.Request) {})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if got := rr.Code == http.StatusOK; got != tc.match {
+			t.Fatalf("%s against %s: match = %v, want %v", tc.path, tc.pattern, got, tc.match)
+		}
+	}
+}
+
+func TestRoutesListsResources(t *testing.T) {
+	r := New()
+	r.Get("/health", func(w http.ResponseWriter, req *http.Request) {})
+	if err := r.Resources("users", &testCtrl{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	routes := r.Routes()
+	if routes[0] != (RouteInfo{Method: "GET", Pattern: "/health"}) {
+		t.Fatalf("expected unnamed /health first, got %+v", routes[0])
+	}
+	want := []RouteInfo{
+		{"GET", "/users", "users_index"},
+		{"GET", "/users/new", "users_new"},
+		{"POST", "/users", "users_create"},
+		{"GET", "/users/:id", "users_show"},
+		{"GET", "/users/:id/edit", "users_edit"},
+		{"PUT", "/users/:id", "users_update"},
+		{"DELETE", "/users/:id", "users_destroy"},
+	}
+	for _, w := range want {
+		found := false
+		for _, got := range routes {
+			if got == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("missing route %+v in %+v", w, routes)
+		}
+	}
+}
+
+func TestHandleExact(t *testing.T) {
+	r := New()
+	write := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write([]byte(body)) }
+	}
+	r.HandleExact("GET", "/dir/", write("collection"))
+	r.HandleExact("GET", "/file", write("file"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/dir/", http.StatusOK, "collection"},
+		{"/dir", http.StatusNotFound, ""},
+		{"/file", http.StatusOK, "file"},
+		{"/file/", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s: expected %q, got %q", tc.path, tc.body, rr.Body.String())
+		}
+	}
+
+	// a normalized route registered after the exact one still serves /dir
+	r.Get("/dir", write("dir"))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/dir", nil))
+	if rr.Body.String() != "dir" {
+		t.Fatalf("expected /dir to reach the normalized route, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDuplicateRoutes(t *testing.T) {
+	ok := func(w http.ResponseWriter, req *http.Request) {}
+
+	r := New()
+	r.Get("/users/:id", ok)
+	r.Put("/users/:id", ok)     // same pattern, different method
+	r.Get("/users/:id|int", ok) // constrained variant is a different pattern
+	r.HandleExact("GET", "/users/:id/", ok)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic registering GET /users/:id twice")
+			}
+		}()
+		r.Get("/users/:id", ok)
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic for a duplicate differing only by trailing slash")
+			}
+		}()
+		r.Get("/users/:id/", ok)
+	}()
+
+	if err := r.TryHandle("PUT", "/users/:id", ok); err == nil {
+		t.Fatalf("expected TryHandle to report the duplicate")
+	}
+	if err := r.TryHandle("DELETE", "/users/:id", ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.TryHandle("GET", "/bad/:id|[", ok); err == nil {
+		t.Fatalf("expected TryHandle to report an invalid constraint")
+	}
+
+	// parameter and wildcard names do not make a route distinct
+	for _, pattern := range []string{"/users/:uid", "/users/:uid|int"} {
+		if err := r.TryHandle("GET", pattern, ok); err == nil {
+			t.Fatalf("expected GET %s to duplicate an existing route", pattern)
+		}
+	}
+	if err := r.TryHandle("GET", "/users/:uid|uuid", ok); err != nil {
+		t.Fatalf("a different constraint is a different route: %v", err)
+	}
+	r.Get("/files/*path", ok)
+	if err := r.TryHandle("GET", "/files/*rest", ok); err == nil {
+		t.Fatalf("expected GET /files/*rest to duplicate /files/*path")
+	}
+}
+
+func TestStatic(t *testing.T) {
+	root := t.TempDir()
+	public := filepath.Join(root, "public")
+	if err := os.MkdirAll(filepath.Join(public, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(public, "css", "app.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("s3cr3t"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.Static("/static", public)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/static/css/app.css", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "body{}" {
+		t.Fatalf("expected app.css, got %d %q", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Fatalf("expected text/css, got %q", ct)
+	}
+
+	for _, p := range []string{"/static/missing.js", "/static/css", "/static"} {
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", p, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", p, rr.Code)
+		}
+	}
+
+	for _, p := range []string{"/static/../secret", "/static/css/../../secret"} {
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", p, nil))
+		if rr.Code == http.StatusOK || strings.Contains(rr.Body.String(), "s3cr3t") {
+			t.Fatalf("%s: traversal not rejected: %d %q", p, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestStaticWith(t *testing.T) {
+	public := t.TempDir()
+	for name, body := range map[string]string{
+		"js/app.js":         "console.log(1)",
+		"docs/index.html":   "<h1>docs</h1>",
+		"private/key.txt":   "s3cr3t",
+		"images/README.txt": "no index here",
+	} {
+		p := filepath.Join(public, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New()
+	r.StaticWith("/assets", public, StaticConfig{
+		Index: "index.html",
+		Authorize: func(req *http.Request, name string) bool {
+			return !strings.HasPrefix(name, "private/")
+		},
+		Headers: map[string]http.Header{".JS": {"Cache-Control": {"public, max-age=31536000"}}},
+	})
+	get := func(p string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", p, nil))
+		return rr
+	}
+
+	if rr := get("/assets/private/key.txt"); rr.Code != http.StatusForbidden || strings.Contains(rr.Body.String(), "s3cr3t") {
+		t.Fatalf("blocked path: expected 403, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr := get("/assets/images"); rr.Code != http.StatusNotFound {
+		t.Fatalf("directory without index: expected 404, got %d", rr.Code)
+	}
+	if rr := get("/assets/docs"); rr.Code != http.StatusOK || rr.Body.String() != "<h1>docs</h1>" {
+		t.Fatalf("directory index: got %d %q", rr.Code, rr.Body.String())
+	}
+	rr := get("/assets/js/app.js")
+	if rr.Code != http.StatusOK || rr.Header().Get("Cache-Control") != "public, max-age=31536000" {
+		t.Fatalf("js headers: got %d %v", rr.Code, rr.Header())
+	}
+	if rr := get("/assets/docs/index.html"); rr.Header().Get("Cache-Control") != "" {
+		t.Fatalf("headers leaked to .html: %v", rr.Header())
+	}
+}
+
+func TestMount(t *testing.T) {
+	api := New()
+	api.Get("/widgets/:id", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("widget " + Param(req, "id") + " org=" + Param(req, "org") + " path=" + req.URL.Path))
+	})
+	api.Get("/", func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write([]byte("api root")) })
+
+	r := New()
+	r.Mount("/api", api)
+	r.Mount("/orgs/:org", api)
+	r.Get("/other", func(w http.ResponseWriter, req *http.Request) {})
+
+	cases := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/api/widgets/5", http.StatusOK, "widget 5 org= path=/widgets/5"},
+		{"GET", "/api", http.StatusOK, "api root"},
+		{"GET", "/orgs/acme/widgets/7", http.StatusOK, "widget 7 org=acme path=/widgets/7"},
+		{"GET", "/api/missing", http.StatusNotFound, ""},
+		{"POST", "/api/widgets/5", http.StatusMethodNotAllowed, ""}, // the sub-router decides
+		{"GET", "/apix/widgets/5", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s %s: expected %q, got %q", tc.method, tc.path, tc.body, rr.Body.String())
+		}
+	}
+}
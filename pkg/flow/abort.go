@@ -0,0 +1,57 @@
+// Package flow: aborting a request from middleware.
+//
+// Middleware written with ContextMiddleware can stop the chain by aborting
+// the Context, eg. an authentication check:
+//
+//	app.Use(flow.ContextMiddleware(app, func(c *flow.Context) {
+//		if c.Session() == nil {
+//			c.AbortWithStatus(http.StatusUnauthorized)
+//		}
+//	}))
+//
+// The aborted flag lives in the request's value store (see Context.Set),
+// so every Context created for the request sees it.
+package flow
+
+import "net/http"
+
+// Abort marks the request as handled: ContextMiddleware will not call the
+// next handler. It does not write a response.
+func (c *Context) Abort() {
+	s := c.store()
+	s.mu.Lock()
+	s.aborted = true
+	s.mu.Unlock()
+}
+
+// IsAborted reports whether Abort was called for this request.
+func (c *Context) IsAborted() bool {
+	s := c.store()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.aborted
+}
+
+// AbortWithStatus writes code as the response status and aborts.
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// ContextMiddleware adapts fn, which works on a *Context, into a
+// Middleware. fn runs before the next handler, which is skipped when fn
+// aborted the request (or an earlier middleware already had).
+func ContextMiddleware(app *App, fn func(*Context)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := NewContext(app, w, r)
+			if !c.IsAborted() {
+				fn(c)
+			}
+			if c.IsAborted() {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
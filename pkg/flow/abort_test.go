@@ -0,0 +1,54 @@
+package flow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextMiddleware_AbortWithStatus(t *testing.T) {
+	app := New("abort-test")
+	var order []string
+	app.Use(ContextMiddleware(app, func(c *Context) {
+		order = append(order, "auth")
+		if c.R.Header.Get("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	}))
+	// a later Context middleware is skipped too
+	app.Use(ContextMiddleware(app, func(c *Context) {
+		order = append(order, "audit")
+	}))
+	r := NewRouter(app)
+	r.Get("/secret", func(c *Context) {
+		order = append(order, "handler")
+		c.JSON(http.StatusOK, map[string]bool{"aborted": c.IsAborted()})
+	})
+	app.SetRouter(r)
+
+	rec := httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/secret", nil))
+	if rec.Code != http.StatusUnauthorized || len(order) != 1 {
+		t.Fatalf("expected 401 with only auth run, got %d and %v", rec.Code, order)
+	}
+
+	order = nil
+	req := httptest.NewRequest("GET", "/secret", nil)
+	req.Header.Set("Authorization", "Bearer t")
+	rec = httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(order) != 3 || rec.Body.String() != "{\"aborted\":false}\n" {
+		t.Fatalf("expected the handler to run, got %d, %v, %s", rec.Code, order, rec.Body.String())
+	}
+}
+
+func TestContext_Abort(t *testing.T) {
+	c := NewContext(nil, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if c.IsAborted() {
+		t.Fatalf("expected a fresh Context not to be aborted")
+	}
+	c.Abort()
+	if !c.IsAborted() {
+		t.Fatalf("expected Abort to mark the Context")
+	}
+}
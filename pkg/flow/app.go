@@ -7,12 +7,12 @@
 //
 // The App is responsible for:
 // - holding configuration (address, timeouts, logger)
-// - accepting a router (http.Handler) or using a default ServeMux
+// - accepting a router (http.Handler) or answering with a JSON 404
 // - registering middleware in a deterministic order
 // - starting and gracefully shutting down the HTTP server
 //
 // TODO: integrate with pkg/flow/router, controller, view and model packages
-// when those modules are implemented. Add lifecycle hooks and health checks.
+// when those modules are implemented. Add lifecycle hooks.
 package flow
 
 import (
@@ -21,10 +21,17 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/netip"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -37,6 +44,20 @@ import (
 // registered earlier will be executed outer-most (first to receive requests).
 type Middleware func(http.Handler) http.Handler
 
+// Phase groups App middleware so ordering doesn't depend on call order.
+// PhasePre runs outer-most (first to see the request, eg. Recovery), then
+// PhaseNormal (Use, WithMiddleware and most With* options), then PhasePost,
+// closest to the router. Within a phase, earlier registrations are outer.
+type Phase int
+
+const (
+	PhasePre Phase = iota
+	PhaseNormal
+	PhasePost
+
+	phaseCount = iota
+)
+
 // Logger defines the subset of logging functionality Flow expects. Users can
 // provide their own logger as long as it implements these methods.
 type Logger interface {
@@ -56,8 +77,8 @@ type App struct {
 
 	logger Logger
 
-	// router is the underlying http.Handler providing routing logic. If nil,
-	// a default http.ServeMux is used.
+	// router is the underlying http.Handler providing routing logic. Until
+	// SetRouter is called it answers every request via NotFoundHandler.
 	router http.Handler
 
 	// Sessions holds the session manager used by the App. If nil, sessions
@@ -67,7 +88,49 @@ type App struct {
 	// Views provides template rendering utilities for controllers and handlers.
 	Views *ViewManager
 
-	middleware []Middleware
+	// Cache holds values shared between requests (see Context.Cache). New
+	// installs a MemoryCache of DefaultCacheSize entries.
+	Cache Cache
+
+	// jsonContentType and problemContentType override the Content-Type
+	// written by Context.JSON and Context.JSONError. Empty means default.
+	jsonContentType    string
+	problemContentType string
+
+	// middleware holds App middleware per Phase
+	middleware [phaseCount][]Middleware
+
+	// mounts holds handlers registered via Mount, dispatched by path prefix
+	// before falling back to router.
+	mounts []mount
+
+	// readiness holds checks registered via AddReadinessCheck.
+	readiness []readinessCheck
+
+	// errorStatuses holds mappings registered via RegisterErrorStatus.
+	errorStatuses []errorStatus
+
+	// notFound answers requests no route matches (see WithNotFoundHandler).
+	// Nil means the default JSON 404.
+	notFound http.Handler
+
+	// maxUploadBytes caps streamed multipart bodies (see WithMaxUploadBytes).
+	// Zero means DefaultMaxUploadBytes.
+	maxUploadBytes int64
+	// multipartMemory is how much of a parsed multipart form is kept in
+	// memory (see WithMultipartMemory). Zero means DefaultMultipartMemory.
+	multipartMemory int64
+	// trustedProxies are the addresses whose forwarding headers ClientIP
+	// believes (see WithTrustedProxies).
+	trustedProxies []netip.Prefix
+	// precompileViews makes Start call Views.Precompile (see
+	// WithViewsPrecompile).
+	precompileViews bool
+	// watchViews makes Start call Views.StartWatching (see WithViewsWatch).
+	watchViews bool
+	// maxHeaderBytes is the server's MaxHeaderBytes (see WithRequestLimits).
+	// Zero means the net/http default.
+	maxHeaderBytes int
 
 	server *http.Server
 	// db is the optional database connection attached to the App.
@@ -75,10 +138,16 @@ type App struct {
 	// bunAdapter holds an optional Bun adapter for ORM operations. If set,
 	// App.Bun() returns the underlying *bun.DB for convenience.
 	bunAdapter *orm.BunAdapter
+	// namedBun holds additional connections registered with SetNamedBun.
+	namedBun map[string]*orm.BunAdapter
 
 	// state indicates whether the server is running: 0 = idle, 1 = running,
 	// 2 = shutting down/stopped.
 	state int32
+	// readyMu guards ready, closed by Start once listener is bound.
+	readyMu  sync.Mutex
+	ready    chan struct{}
+	listener net.Listener
 }
 
 // SetBun attaches a BunAdapter to the App and also sets the underlying *sql.DB
@@ -102,6 +171,37 @@ func (a *App) Bun() *bun.DB {
 	return a.bunAdapter.DB
 }
 
+// SetNamedBun attaches an additional Bun connection under name, eg. a
+// reporting database next to the primary one. The empty name is the
+// default connection (SetBun); a nil adapter removes the connection.
+// Connections are meant to be configured before the App serves requests.
+func (a *App) SetNamedBun(name string, b *orm.BunAdapter) {
+	if name == "" {
+		a.SetBun(b)
+		return
+	}
+	if b == nil {
+		delete(a.namedBun, name)
+		return
+	}
+	if a.namedBun == nil {
+		a.namedBun = map[string]*orm.BunAdapter{}
+	}
+	a.namedBun[name] = b
+}
+
+// NamedBun returns the *bun.DB registered under name, or nil. The empty
+// name returns Bun().
+func (a *App) NamedBun(name string) *bun.DB {
+	if name == "" {
+		return a.Bun()
+	}
+	if a == nil || a.namedBun[name] == nil {
+		return nil
+	}
+	return a.namedBun[name].DB
+}
+
 var (
 	// ErrAppAlreadyRunning is returned when Start/Run is called on an already-running App.
 	ErrAppAlreadyRunning = errors.New("app: already running")
@@ -110,7 +210,9 @@ var (
 // Option is a functional option for configuring an App at construction time.
 type Option func(*App)
 
-// WithLogger sets a custom logger. If not provided, the standard log.Logger is used.
+// WithLogger sets a custom logger. If not provided, the standard log.Logger
+// is used (or NopLogger when FLOW_QUIET is set). Use NopLogger to silence
+// the App and NewTestLogger to capture output in tests.
 func WithLogger(l Logger) Option {
 	return func(a *App) { a.logger = l }
 }
@@ -120,6 +222,12 @@ func WithBun(b *orm.BunAdapter) Option {
 	return func(a *App) { a.SetBun(b) }
 }
 
+// WithNamedBun attaches a named Bun connection during construction (see
+// App.SetNamedBun).
+func WithNamedBun(name string, b *orm.BunAdapter) Option {
+	return func(a *App) { a.SetNamedBun(name, b) }
+}
+
 // WithAddr sets the listen address (eg. ":3000").
 func WithAddr(addr string) Option {
 	return func(a *App) { a.Addr = addr }
@@ -158,6 +266,36 @@ func WithViewsDevMode(dev bool) Option {
 	}
 }
 
+// WithViewsBuffered toggles buffered rendering (on by default). Buffered
+// views are written only after the template executes successfully, so a
+// failing template yields a clean error response instead of a partial
+// page. Disable it for templates that stream or flush output.
+func WithViewsBuffered(buffered bool) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.SetBuffered(buffered)
+	}
+}
+
+// WithViewsDelims sets custom template delimiters, eg. "[[" and "]]", so
+// views can embed front-end templates that use "{{ }}" themselves.
+func WithViewsDelims(left, right string) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.Delims(left, right)
+	}
+}
+
 // WithViewsFuncMap sets the template FuncMap on the ViewManager during App construction.
 func WithViewsFuncMap(m template.FuncMap) Option {
 	return func(a *App) {
@@ -171,6 +309,118 @@ func WithViewsFuncMap(m template.FuncMap) Option {
 	}
 }
 
+// WithViewsContentBlock sets the template block name the ViewManager
+// executes on Render (default "content").
+func WithViewsContentBlock(name string) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.SetContentBlock(name)
+	}
+}
+
+// WithViewsPartialDirs registers additional partial directories (relative to
+// the views directory) that are walked recursively when loading templates.
+func WithViewsPartialDirs(dirs ...string) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.SetPartialDirs(dirs...)
+	}
+}
+
+// WithViewsStrictDefines makes the ViewManager return an error when two
+// template files define the same block instead of letting the last one win.
+func WithViewsStrictDefines() Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.SetStrictDefines(true)
+	}
+}
+
+// WithViewsFS loads views from fsys (eg. an embed.FS) instead of the views
+// directory on disk; see ViewManager.SetFS.
+func WithViewsFS(fsys fs.FS) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.Views.SetFS(fsys)
+	}
+}
+
+// WithViewsPrecompile makes Start parse and cache every view before it
+// listens, so misconfigured templates fail at boot rather than on the first
+// request: Start returns the first parse error. The views are read from the
+// TemplateDir (or FS) configured by then. Apps served without Start call
+// Views.Precompile themselves.
+func WithViewsPrecompile() Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.precompileViews = true
+	}
+}
+
+// WithViewsWatch watches the views directory while the App runs, reloading
+// cached templates when their files change. The watcher starts with Start,
+// so it covers the TemplateDir configured by then, and stops on Shutdown;
+// apps served without Start (eg. via ServeHTTP) call Views.StartWatching
+// themselves. Failures to start the watcher are logged and leave the
+// ViewManager in its normal caching mode.
+func WithViewsWatch() Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if a.Views == nil {
+			a.Views = NewViewManager("views")
+		}
+		a.watchViews = true
+	}
+}
+
+// WithCache replaces the App's default MemoryCache, eg. with a larger
+// NewMemoryCache or a shared implementation.
+func WithCache(c Cache) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.Cache = c
+	}
+}
+
+// WithJSONContentType sets the Content-Type used by Context.JSON
+// (default "application/json; charset=utf-8"), eg. "application/json" for
+// clients that reject the charset parameter.
+func WithJSONContentType(ct string) Option {
+	return func(a *App) { a.jsonContentType = ct }
+}
+
+// WithJSONErrorContentType sets the Content-Type used by Context.JSONError
+// (default "application/problem+json").
+func WithJSONErrorContentType(ct string) Option {
+	return func(a *App) { a.problemContentType = ct }
+}
+
 // WithLogging registers the built-in logging middleware using the App's logger.
 func WithLogging() Option {
 	return func(a *App) {
@@ -181,6 +431,28 @@ func WithLogging() Option {
 	}
 }
 
+// WithConcurrencyLimit caps how many requests the App handles at once;
+// the rest get 503 (see Concurrency). n <= 0 leaves it unlimited.
+func WithConcurrencyLimit(n int) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.Use(Concurrency(n))
+	}
+}
+
+// WithPathNormalizer registers PathNormalizer so paths are cleaned (and
+// optionally lowercased) before they reach the router or mounts.
+func WithPathNormalizer(lowercase bool) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.Use(PathNormalizer(lowercase))
+	}
+}
+
 // WithRequestID registers the request ID middleware. If headerName is empty
 // the default header "X-Request-ID" is used.
 func WithRequestID(headerName string) Option {
@@ -213,14 +485,206 @@ func WithMetrics() Option {
 	}
 }
 
+// WithCompression registers CompressMiddleware. gzip is always available;
+// pass additional encoders (eg. Brotli) to prefer them when clients accept
+// them.
+func WithCompression(extra ...Encoder) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.Use(CompressMiddleware(extra...))
+	}
+}
+
+// WithPprof mounts the net/http/pprof handlers under prefix (default
+// "/debug/pprof"). Profiling is off unless this option is used; pass
+// middleware (eg. an authentication check) to protect the endpoints. Flow
+// never serves http.DefaultServeMux, where net/http/pprof also registers
+// itself, so the endpoints are only reachable through this mount.
+func WithPprof(prefix string, mws ...Middleware) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if prefix == "" {
+			prefix = "/debug/pprof"
+		}
+		var h http.Handler = pprofHandler()
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		a.Mount(prefix, h)
+	}
+}
+
+// WithNotFoundHandler overrides the response for requests that match no
+// route. By default the App answers with a JSON problem (see
+// Context.JSONError) and status 404; routers built with NewRouter use the
+// same handler.
+func WithNotFoundHandler(h http.Handler) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.notFound = h
+	}
+}
+
+// NotFoundHandler returns the handler for unmatched requests: the one set
+// via WithNotFoundHandler, or the default JSON 404.
+func (a *App) NotFoundHandler() http.Handler {
+	if a.notFound != nil {
+		return a.notFound
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = NewContext(a, w, r).JSONError(http.StatusNotFound, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	})
+}
+
+// serveNotFound dispatches to NotFoundHandler at request time, so the
+// handler can be configured after a router captured it.
+func (a *App) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	a.NotFoundHandler().ServeHTTP(w, r)
+}
+
+// WithMaxUploadBytes caps the total size of request bodies read through
+// Context.MultipartReader and Context.StreamUpload (default
+// DefaultMaxUploadBytes).
+func WithMaxUploadBytes(n int64) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.maxUploadBytes = n
+	}
+}
+
+// WithMaxBodyBytes caps request bodies for the whole App at n bytes (see
+// MaxBodyBytes, registered in PhasePre so the limit applies before any
+// other middleware reads the body). n <= 0 leaves bodies unlimited.
+func WithMaxBodyBytes(n int64) Option {
+	return func(a *App) {
+		if a == nil || n <= 0 {
+			return
+		}
+		a.UseIn(PhasePre, MaxBodyBytes(n))
+	}
+}
+
+// WithRequestLimits hardens the server against oversized requests: bodies
+// over maxBody bytes get 413 (see MaxBodyBytes, registered in PhasePre) and
+// request headers over maxHeader bytes get 431 from net/http (the server's
+// MaxHeaderBytes). Zero or negative values leave that limit unchanged.
+func WithRequestLimits(maxBody int64, maxHeader int) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		WithMaxBodyBytes(maxBody)(a)
+		if maxHeader > 0 {
+			a.maxHeaderBytes = maxHeader
+		}
+	}
+}
+
+// WithTrustedProxies sets the proxies (CIDRs or single IPs) whose
+// X-Forwarded-For and X-Real-IP headers Context.ClientIP honours. It panics
+// on an invalid entry; use App.SetTrustedProxies to handle the error.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if err := a.SetTrustedProxies(cidrs...); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// WithMultipartMemory sets how much of a multipart form Context.FormFile
+// and Context.BindForm hold in memory before file parts spill to temporary
+// files (default DefaultMultipartMemory).
+func WithMultipartMemory(n int64) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.multipartMemory = n
+	}
+}
+
+// WithDBStats serves the database pool statistics (see App.DBStats) as JSON
+// under path (default "/debug/dbstats"). The endpoint is off unless this
+// option is used; pass middleware (eg. an authentication check) to protect
+// it.
+func WithDBStats(path string, mws ...Middleware) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		if path == "" {
+			path = "/debug/dbstats"
+		}
+		h := a.DBStatsHandler()
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		a.Mount(path, h)
+	}
+}
+
+// pprofHandler serves the pprof endpoints relative to its mount point.
+func pprofHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch name := strings.TrimPrefix(r.URL.Path, "/"); name {
+		case "":
+			pprof.Index(w, r)
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+	})
+}
+
+// WithMiddleware appends mws to the PhaseNormal stack, like Use, at the
+// point where the option appears among New's options.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.UseIn(PhaseNormal, mws...)
+	}
+}
+
+// WithRecovery registers Recovery in PhasePre so panics anywhere in the
+// middleware stack or handlers become 500 responses.
+func WithRecovery() Option {
+	return func(a *App) {
+		if a == nil {
+			return
+		}
+		a.UseIn(PhasePre, Recovery(a.logger))
+	}
+}
+
 // WithDefaultMiddleware registers a sensible default middleware stack:
-// Recovery, RequestID, Logging and Metrics.
+// Recovery (in PhasePre, so it wraps everything), RequestID, Logging and
+// Metrics.
 func WithDefaultMiddleware() Option {
 	return func(a *App) {
 		if a == nil {
 			return
 		}
-		a.Use(Recovery(a.logger))
+		a.UseIn(PhasePre, Recovery(a.logger))
 		a.Use(RequestIDMiddleware(""))
 		a.Use(LoggingMiddleware(a.logger))
 		a.Use(MetricsMiddleware())
@@ -230,7 +694,10 @@ func WithDefaultMiddleware() Option {
 // New creates a configured App instance. It never starts network listeners.
 func New(name string, opts ...Option) *App {
 	// default logger
-	stdLogger := log.New(os.Stdout, "[flow] ", log.LstdFlags)
+	var stdLogger Logger = log.New(os.Stdout, "[flow] ", log.LstdFlags)
+	if quietRequested() {
+		stdLogger = NopLogger()
+	}
 
 	a := &App{
 		Name:            name,
@@ -240,12 +707,13 @@ func New(name string, opts ...Option) *App {
 		IdleTimeout:     120 * time.Second,
 		ShutdownTimeout: 10 * time.Second,
 		logger:          stdLogger,
-		router:          http.NewServeMux(),
 		Views:           NewViewManager("views"),
 		Sessions:        DefaultSessionManager(),
-		middleware:      make([]Middleware, 0),
+		Cache:           NewMemoryCache(DefaultCacheSize),
 	}
 
+	a.router = http.HandlerFunc(a.serveNotFound)
+
 	for _, opt := range opts {
 		opt(a)
 	}
@@ -253,52 +721,185 @@ func New(name string, opts ...Option) *App {
 	return a
 }
 
-// Use appends middleware to the middleware stack.
+// Use appends middleware to the PhaseNormal stack.
 // Middlewares are applied in registration order with the first registered
-// being the outer-most wrapper.
+// being the outer-most wrapper. Options passed to New run before any Use
+// call, so option middleware in the same phase wraps middleware added with
+// Use afterwards.
 func (a *App) Use(m Middleware) {
-	a.middleware = append(a.middleware, m)
+	a.UseIn(PhaseNormal, m)
+}
+
+// UseIn appends middleware to the given phase, eg. UseIn(PhasePre, mw) for
+// middleware that must wrap everything else regardless of when it is
+// registered.
+func (a *App) UseIn(phase Phase, mws ...Middleware) {
+	if phase < PhasePre || phase > PhasePost {
+		phase = PhaseNormal
+	}
+	a.middleware[phase] = append(a.middleware[phase], mws...)
 }
 
-// SetRouter replaces the App's router. If nil is provided the default
-// ServeMux is used.
+// SetRouter replaces the App's router. If nil is provided every request is
+// answered by the App's not-found handler.
 func (a *App) SetRouter(h http.Handler) {
 	if h == nil {
-		h = http.NewServeMux()
+		h = http.HandlerFunc(a.serveNotFound)
 	}
 	a.router = h
 }
 
-// Handler builds the final http.Handler by applying middleware to the router.
+// Router returns the handler set via SetRouter (or the default not-found
+// handler), without App middleware or mounts applied. It is useful for
+// inspecting routes in tests or composing the App's routes into another
+// handler.
+func (a *App) Router() http.Handler {
+	return a.router
+}
+
+// SetRouterFunc builds a Router bound to the App, lets fn register routes
+// on it and installs it as the App's router:
+//
+//	app.SetRouterFunc(func(r *flow.Router) {
+//		r.Get("/health", healthHandler)
+//	})
+func (a *App) SetRouterFunc(fn func(*Router)) {
+	r := NewRouter(a)
+	if fn != nil {
+		fn(r)
+	}
+	a.SetRouter(r)
+}
+
+// mount pairs a path prefix with the handler serving it.
+type mount struct {
+	prefix  string
+	handler http.Handler
+}
+
+// Mount registers h to serve all requests whose path is prefix or lies
+// beneath it (matching whole segments, so "/api" does not match "/apix").
+// The prefix is stripped before h is invoked, so a mounted router declares
+// its routes relative to the mount point. Mounted handlers are independent
+// sub-apps: App-level middleware wraps them (outer) and any middleware they
+// apply themselves runs inside. When prefixes overlap the longest wins;
+// unmatched requests go to the App's router.
+func (a *App) Mount(prefix string, h http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		a.SetRouter(h)
+		return
+	}
+	a.mounts = append(a.mounts, mount{prefix: prefix, handler: h})
+	sort.SliceStable(a.mounts, func(i, j int) bool {
+		return len(a.mounts[i].prefix) > len(a.mounts[j].prefix)
+	})
+}
+
+// dispatch routes the request to the longest matching mount or the router.
+func (a *App) dispatch(w http.ResponseWriter, r *http.Request) {
+	for _, m := range a.mounts {
+		p := r.URL.Path
+		if p != m.prefix && !strings.HasPrefix(p, m.prefix+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(p, m.prefix)
+		if rest == "" {
+			rest = "/"
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = rest
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, m.prefix)
+			if r2.URL.RawPath == "" {
+				r2.URL.RawPath = "/"
+			}
+		}
+		m.handler.ServeHTTP(w, r2)
+		return
+	}
+	a.router.ServeHTTP(w, r)
+}
+
+// Handler builds the final http.Handler by applying middleware to the router
+// (and any mounted handlers).
 func (a *App) Handler() http.Handler {
 	var h http.Handler = a.router
-	// Apply middleware in reverse so the first registered is outer-most.
-	for i := len(a.middleware) - 1; i >= 0; i-- {
-		h = a.middleware[i](h)
+	if len(a.mounts) > 0 {
+		h = http.HandlerFunc(a.dispatch)
+	}
+	// Apply middleware in reverse so PhasePre and, within a phase, the
+	// first registered are outer-most.
+	for p := len(a.middleware) - 1; p >= 0; p-- {
+		mws := a.middleware[p]
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+	}
+	// record the start time and share Context values outside all
+	// middleware (see Context.StartTime and Context.Set)
+	return withStartTime(withValues(h))
+}
+
+// newServer builds the http.Server Start runs, from the App's settings.
+func (a *App) newServer() *http.Server {
+	return &http.Server{
+		Addr:           a.Addr,
+		Handler:        a.Handler(),
+		ReadTimeout:    a.ReadTimeout,
+		WriteTimeout:   a.WriteTimeout,
+		IdleTimeout:    a.IdleTimeout,
+		MaxHeaderBytes: a.maxHeaderBytes,
 	}
-	return h
 }
 
-// Start starts the HTTP server in a background goroutine and returns immediately.
-// It returns ErrAppAlreadyRunning if called while the server is already running.
+// Start binds the listen address and serves HTTP in a background goroutine,
+// returning once the listener accepts connections (see Ready). A bind
+// failure, eg. an address in use, is returned, as is a broken view when
+// WithViewsPrecompile is set. It returns
+// ErrAppAlreadyRunning if called while the server is already running.
 func (a *App) Start() error {
 	if !atomic.CompareAndSwapInt32(&a.state, 0, 1) {
 		return ErrAppAlreadyRunning
 	}
 
-	srv := &http.Server{
-		Addr:         a.Addr,
-		Handler:      a.Handler(),
-		ReadTimeout:  a.ReadTimeout,
-		WriteTimeout: a.WriteTimeout,
-		IdleTimeout:  a.IdleTimeout,
+	if a.precompileViews {
+		if err := a.Views.Precompile(); err != nil {
+			atomic.StoreInt32(&a.state, 0)
+			return err
+		}
+	}
+	if a.watchViews {
+		if err := a.Views.StartWatching(); err != nil {
+			a.logger.Printf("views: %v", err)
+		}
+	}
+
+	srv := a.newServer()
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		if a.watchViews {
+			_ = a.Views.StopWatching()
+		}
+		atomic.StoreInt32(&a.state, 0)
+		return fmt.Errorf("listen %s: %w", addr, err)
 	}
 	a.server = srv
 
+	ready := a.readyChan()
+	a.readyMu.Lock()
+	a.listener = ln
+	a.readyMu.Unlock()
+	close(ready)
+
 	go func() {
-		a.logger.Printf("starting %s on %s", a.Name, a.Addr)
+		a.logger.Printf("starting %s on %s", a.Name, ln.Addr())
 		// http.ErrServerClosed is returned on normal shutdown and should not be logged as an error
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
 			a.logger.Printf("server error: %v", err)
 		}
 		// transition to stopped
@@ -308,6 +909,36 @@ func (a *App) Start() error {
 	return nil
 }
 
+// Ready returns a channel closed once Start (or Run) has bound the
+// listener, so tests and supervisors can wait for the server to accept
+// connections instead of sleeping:
+//
+//	go app.Run(ctx)
+//	<-app.Ready()
+func (a *App) Ready() <-chan struct{} {
+	return a.readyChan()
+}
+
+func (a *App) readyChan() chan struct{} {
+	a.readyMu.Lock()
+	defer a.readyMu.Unlock()
+	if a.ready == nil {
+		a.ready = make(chan struct{})
+	}
+	return a.ready
+}
+
+// ListenAddr returns the address the server is bound to, eg. the port
+// picked for an Addr of ":0", or nil before Start.
+func (a *App) ListenAddr() net.Addr {
+	a.readyMu.Lock()
+	defer a.readyMu.Unlock()
+	if a.listener == nil {
+		return nil
+	}
+	return a.listener.Addr()
+}
+
 // Run starts the server and blocks until a termination signal is received or
 // the context is canceled. It performs a graceful shutdown with the configured
 // ShutdownTimeout.
@@ -339,8 +970,12 @@ func (a *App) Run(ctx context.Context) error {
 	return a.Shutdown(ctxShutdown)
 }
 
-// Shutdown gracefully stops the HTTP server. It is safe to call multiple times.
+// Shutdown gracefully stops the HTTP server and the views watcher. It is
+// safe to call multiple times.
 func (a *App) Shutdown(ctx context.Context) error {
+	if err := a.Views.StopWatching(); err != nil {
+		a.logger.Printf("views: %v", err)
+	}
 	// if server is nil, nothing to do
 	if a.server == nil {
 		return nil
@@ -0,0 +1,266 @@
+package flow
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApp_MountSubRouters(t *testing.T) {
+	app := New("mount-test")
+
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	app.Use(tag("app"))
+
+	api := NewRouter(app)
+	api.Get("/users/:id", func(ctx *Context) {
+		_, _ = ctx.W.Write([]byte("api user " + ctx.Param("id")))
+	})
+	admin := NewRouter(app)
+	admin.Get("/", func(ctx *Context) {
+		_, _ = ctx.W.Write([]byte("admin home"))
+	})
+
+	app.Mount("/api", tag("api")(api))
+	app.Mount("/admin", admin)
+
+	root := NewRouter(app)
+	root.Get("/", func(ctx *Context) { _, _ = ctx.W.Write([]byte("root")) })
+	app.SetRouter(root)
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/users/7", http.StatusOK, "api user 7"},
+		{"/admin", http.StatusOK, "admin home"},
+		{"/", http.StatusOK, "root"},
+		{"/apix/users/7", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s: unexpected body %q", tc.path, rr.Body.String())
+		}
+	}
+
+	// app middleware runs outside the sub-router's own middleware
+	order = nil
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/1", nil))
+	if len(order) != 2 || order[0] != "app" || order[1] != "api" {
+		t.Fatalf("unexpected middleware order: %v", order)
+	}
+}
+
+func TestApp_WithPprof(t *testing.T) {
+	app := New("pprof-on", WithPprof(""))
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 with pprof enabled, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/pprof/heap?debug=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for heap profile, got %d", rr.Code)
+	}
+
+	off := New("pprof-off")
+	rr = httptest.NewRecorder()
+	off.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without pprof, got %d", rr.Code)
+	}
+}
+
+func TestApp_RouterReturnsSetRouter(t *testing.T) {
+	app := New("testapp")
+	app.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	r := NewRouter(app)
+	app.SetRouter(r)
+	if app.Router() != http.Handler(r) {
+		t.Fatalf("expected Router() to return the handler passed to SetRouter")
+	}
+
+	app.SetRouterFunc(func(r *Router) {
+		r.Get("/ping", func(ctx *Context) { _ = ctx.JSON(http.StatusOK, "pong") })
+	})
+	rr := httptest.NewRecorder()
+	app.Router().ServeHTTP(rr, httptest.NewRequest("GET", "/ping", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from SetRouterFunc route, got %d", rr.Code)
+	}
+	if rr.Header().Get("X-Middleware") != "" {
+		t.Fatalf("Router() should not apply App middleware")
+	}
+}
+
+func TestApp_WithNotFoundHandler(t *testing.T) {
+	custom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "custom missing", http.StatusNotFound)
+	})
+	app := New("testapp", WithNotFoundHandler(custom))
+	app.SetRouterFunc(func(r *Router) {
+		r.Get("/ok", func(ctx *Context) { ctx.Status(http.StatusOK) })
+	})
+
+	rr := httptest.NewRecorder()
+	app.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/missing", nil))
+	if rr.Code != http.StatusNotFound || rr.Body.String() != "custom missing\n" {
+		t.Fatalf("expected custom 404, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	// an App without a router answers with the default JSON 404
+	rr = httptest.NewRecorder()
+	New("bare").Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusNotFound || rr.Header().Get("Content-Type") != DefaultProblemContentType {
+		t.Fatalf("expected JSON 404 from bare App, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestApp_MiddlewarePhases(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	preOption := func(a *App) { a.UseIn(PhasePre, tag("pre-option")) }
+
+	app := New("phases-test",
+		WithMiddleware(tag("option-1")),
+		preOption,
+		WithMiddleware(tag("option-2")),
+	)
+	app.UseIn(PhasePost, tag("post"))
+	app.Use(tag("use"))
+	app.UseIn(PhasePre, tag("pre-late"))
+	app.SetRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "router")
+	}))
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	want := []string{"pre-option", "pre-late", "option-1", "option-2", "use", "post", "router"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, order)
+		}
+	}
+}
+
+func TestApp_WithRecoveryWrapsEarlierMiddleware(t *testing.T) {
+	boom := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	}
+	// Recovery is requested after the panicking middleware but still wraps it
+	app := New("recovery-test", WithLogger(NopLogger()), WithMiddleware(boom), WithRecovery())
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 from Recovery, got %d", rr.Code)
+	}
+}
+
+func TestApp_ReadyAfterListen(t *testing.T) {
+	app := New("ready-test", WithAddr("127.0.0.1:0"), WithLogger(NopLogger()))
+	r := NewRouter(app)
+	r.Get("/ping", func(ctx *Context) { ctx.W.Write([]byte("pong")) })
+	app.SetRouter(r)
+
+	select {
+	case <-app.Ready():
+		t.Fatalf("Ready closed before Run")
+	default:
+	}
+	if app.ListenAddr() != nil {
+		t.Fatalf("expected no listen address before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+
+	<-app.Ready()
+	res, err := http.Get("http://" + app.ListenAddr().String() + "/ping")
+	if err != nil {
+		t.Fatalf("get after ready: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Fatalf("got %d %q", res.StatusCode, body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func TestApp_StartReportsBindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	app := New("bind-test", WithAddr(ln.Addr().String()), WithLogger(NopLogger()))
+	if err := app.Start(); err == nil {
+		app.Shutdown(context.Background())
+		t.Fatalf("expected a bind error for an address in use")
+	}
+	select {
+	case <-app.Ready():
+		t.Fatalf("Ready closed after a failed Start")
+	default:
+	}
+}
+
+func TestApp_WithViewsWatchStartsAndStops(t *testing.T) {
+	app := New("watch-test", WithAddr("127.0.0.1:0"), WithLogger(NopLogger()), WithViewsWatch())
+	// set after New: the watcher must pick it up when Start runs
+	app.Views.TemplateDir = t.TempDir()
+	if app.Views.watcher != nil {
+		t.Fatalf("watcher started before Start")
+	}
+
+	if err := app.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if app.Views.watcher == nil {
+		t.Fatalf("expected Start to watch %s", app.Views.TemplateDir)
+	}
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if app.Views.watcher != nil {
+		t.Fatalf("expected Shutdown to stop the watcher")
+	}
+}
@@ -0,0 +1,207 @@
+// Package flow: binding request values into structs.
+//
+// BindURI fills struct fields from path parameters using `uri:"name"` tags:
+//
+//	var p struct {
+//		OrgID int64 `uri:"org_id"`
+//		ID    int64 `uri:"id"`
+//	}
+//	if err := ctx.BindURI(&p); err != nil { ... }
+//
+// BindQuery does the same for the query string with `query:"name"` tags;
+// slice fields collect repeated parameters (?tag=a&tag=b), and BindForm for
+// form bodies with `form:"name"` tags.
+//
+// A dst that implements Validator is checked after BindJSON, BindForm and
+// BindAndValidate decode into it:
+//
+//	func (p *PostParams) Validate() error {
+//		if p.Title == "" {
+//			return errors.New("title is required")
+//		}
+//		return nil
+//	}
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+)
+
+// Validator is implemented by bind targets that can check themselves.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError wraps the error returned by a Validator so handlers can
+// tell invalid input from malformed input with errors.As. It also matches
+// ErrValidation, so Fail responds 422.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return "validation: " + e.Err.Error() }
+
+// Unwrap returns the Validator's error.
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// validate runs dst's Validate method, if any, wrapping a failure in
+// ValidationError.
+func validate(dst interface{}) error {
+	v, ok := dst.(Validator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			return err
+		}
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+// BindForm copies form values (the URL query and an urlencoded or
+// multipart body) into the fields of dst tagged `form:"name"`, like
+// BindQuery, then validates dst when it implements Validator.
+func (c *Context) BindForm(dst interface{}) error {
+	if err := c.R.ParseForm(); err != nil {
+		return fmt.Errorf("bind form: %w", err)
+	}
+	if mt, _, _ := mime.ParseMediaType(c.R.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		if err := c.R.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return fmt.Errorf("bind form: %w", err)
+		}
+	}
+	form := c.R.Form
+	if err := bindTagged("bind form", "form", dst, func(name string) ([]string, bool) {
+		v, ok := form[name]
+		return v, ok && len(v) > 0
+	}); err != nil {
+		return err
+	}
+	return validate(dst)
+}
+
+// BindAndValidate binds the request body into dst according to its
+// Content-Type (JSON, or form data via BindForm) and validates dst when it
+// implements Validator. Validation failures are *ValidationError.
+func (c *Context) BindAndValidate(dst interface{}) error {
+	mt, _, _ := mime.ParseMediaType(c.R.Header.Get("Content-Type"))
+	switch mt {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return c.BindForm(dst)
+	}
+	return c.BindJSON(dst)
+}
+
+// BindURI copies path parameters into the fields of dst (a pointer to a
+// struct) tagged `uri:"name"`, converting them to the field's type
+// (string, bool, signed/unsigned integers or floats). Parameters missing
+// from the route leave their field untouched. Conversion failures name the
+// field and the offending value.
+func (c *Context) BindURI(dst interface{}) error {
+	params := c.Params()
+	return bindTagged("bind uri", "uri", dst, func(name string) ([]string, bool) {
+		v, ok := params[name]
+		return []string{v}, ok
+	})
+}
+
+// BindQuery copies query parameters into the fields of dst (a pointer to a
+// struct) tagged `query:"name"`, eg. for filter and pagination endpoints.
+// Slice fields receive every value of a repeated parameter; other fields
+// take the first. Missing parameters leave their field untouched, and
+// conversion failures name the field and the offending value.
+func (c *Context) BindQuery(dst interface{}) error {
+	q := c.R.URL.Query()
+	return bindTagged("bind query", "query", dst, func(name string) ([]string, bool) {
+		v, ok := q[name]
+		return v, ok && len(v) > 0
+	})
+}
+
+// bindTagged sets each field of the struct pointed to by dst whose tag
+// names values returned by lookup. Slice fields get all values, other
+// fields the first. op prefixes errors.
+func bindTagged(op, tag string, dst interface{}, lookup func(string) ([]string, bool)) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: dst must be a non-nil pointer to a struct, got %T", op, dst)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name := f.Tag.Get(tag)
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setFieldValues(rv.Field(i), raw); err != nil {
+			return fmt.Errorf("%s: field %s (%s): %w", op, f.Name, name, err)
+		}
+	}
+	return nil
+}
+
+// setFieldValues sets v from raw: every value for a slice ([]byte
+// excepted), the first one otherwise.
+func setFieldValues(v reflect.Value, raw []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+		for i, r := range raw {
+			if err := setFieldString(s.Index(i), r); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setFieldString(v, raw[0])
+}
+
+// setFieldString parses raw into v according to v's kind.
+func setFieldString(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
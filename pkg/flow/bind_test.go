@@ -0,0 +1,139 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContext_BindURI(t *testing.T) {
+	app := New("test-app")
+	r := NewRouter(app)
+
+	type userPath struct {
+		OrgID int   `uri:"org_id"`
+		ID    int64 `uri:"id"`
+	}
+	var got userPath
+	var bindErr error
+	r.Get("/orgs/:org_id/users/:id", func(ctx *Context) {
+		got = userPath{}
+		bindErr = ctx.BindURI(&got)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orgs/12/users/345", nil))
+	if bindErr != nil {
+		t.Fatalf("bind uri: %v", bindErr)
+	}
+	if got.OrgID != 12 || got.ID != 345 {
+		t.Fatalf("unexpected binding %+v", got)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orgs/acme/users/345", nil))
+	if bindErr == nil || !strings.Contains(bindErr.Error(), "OrgID") || !strings.Contains(bindErr.Error(), `"acme"`) {
+		t.Fatalf("expected descriptive conversion error, got %v", bindErr)
+	}
+
+	ctx := NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err := ctx.BindURI(got); err == nil {
+		t.Fatalf("expected error for non-pointer dst")
+	}
+}
+
+func TestContext_BindQuery(t *testing.T) {
+	type filter struct {
+		Q       string   `query:"q"`
+		Page    int      `query:"page"`
+		Active  bool     `query:"active"`
+		Tags    []string `query:"tag"`
+		IDs     []int64  `query:"id"`
+		PerPage int      `query:"per_page"`
+	}
+	bind := func(query string) (filter, error) {
+		ctx := NewContext(nil, httptest.NewRecorder(), httptest.NewRequest("GET", "/posts?"+query, nil))
+		f := filter{PerPage: 25}
+		return f, ctx.BindQuery(&f)
+	}
+
+	f, err := bind("q=go+web&page=3&active=true&tag=a&tag=b&id=1&id=2")
+	if err != nil {
+		t.Fatalf("bind query: %v", err)
+	}
+	if f.Q != "go web" || f.Page != 3 || !f.Active {
+		t.Fatalf("unexpected scalars: %+v", f)
+	}
+	if len(f.Tags) != 2 || f.Tags[0] != "a" || f.Tags[1] != "b" {
+		t.Fatalf("expected repeated tags [a b], got %v", f.Tags)
+	}
+	if len(f.IDs) != 2 || f.IDs[1] != 2 {
+		t.Fatalf("expected ids [1 2], got %v", f.IDs)
+	}
+	if f.PerPage != 25 {
+		t.Fatalf("missing param should leave the field untouched, got %d", f.PerPage)
+	}
+
+	_, err = bind("page=two")
+	if err == nil || !strings.Contains(err.Error(), "Page") || !strings.Contains(err.Error(), `"two"`) {
+		t.Fatalf("expected error naming the field, got %v", err)
+	}
+	if _, err := bind("id=1&id=x"); err == nil || !strings.Contains(err.Error(), "IDs") {
+		t.Fatalf("expected error naming the slice field, got %v", err)
+	}
+}
+
+// signup validates itself after binding.
+type signup struct {
+	Email string `json:"email" form:"email"`
+	Age   int    `json:"age" form:"age"`
+}
+
+func (s *signup) Validate() error {
+	if s.Email == "" {
+		return errors.New("email is required")
+	}
+	return nil
+}
+
+func TestContext_BindValidates(t *testing.T) {
+	jsonReq := func(body string) *Context {
+		req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return NewContext(nil, httptest.NewRecorder(), req)
+	}
+	formReq := func(body string) *Context {
+		req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return NewContext(nil, httptest.NewRecorder(), req)
+	}
+
+	var s signup
+	if err := jsonReq(`{"email":"a@example.com","age":30}`).BindJSON(&s); err != nil || s.Age != 30 {
+		t.Fatalf("expected valid json to bind, got %+v, %v", s, err)
+	}
+	if err := formReq("email=b%40example.com&age=41").BindAndValidate(&s); err != nil || s.Email != "b@example.com" || s.Age != 41 {
+		t.Fatalf("expected valid form to bind, got %+v, %v", s, err)
+	}
+
+	for name, bind := range map[string]func(*signup) error{
+		"BindJSON":        func(d *signup) error { return jsonReq(`{"age":30}`).BindJSON(d) },
+		"BindForm":        func(d *signup) error { return formReq("age=30").BindForm(d) },
+		"BindAndValidate": func(d *signup) error { return jsonReq(`{"age":30}`).BindAndValidate(d) },
+	} {
+		err := bind(&signup{})
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Err.Error() != "email is required" {
+			t.Fatalf("%s: expected ValidationError, got %v", name, err)
+		}
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("%s: expected the error to match ErrValidation", name)
+		}
+	}
+
+	// malformed input is not a validation error
+	err := jsonReq(`{"age":`).BindAndValidate(&signup{})
+	var ve *ValidationError
+	if err == nil || errors.As(err, &ve) {
+		t.Fatalf("expected a decode error, got %v", err)
+	}
+}
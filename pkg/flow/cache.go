@@ -0,0 +1,177 @@
+// Package flow: an in-process cache for handlers.
+//
+// App.Cache (and Context.Cache) holds values that are expensive to compute,
+// eg. a rendered homepage:
+//
+//	v, err := ctx.Cache().GetOrSet("home", time.Minute, func() (interface{}, error) {
+//		return buildHomepage(ctx)
+//	})
+//
+// The default MemoryCache keeps up to DefaultCacheSize entries; replace it
+// with WithCache, eg. to share entries between instances.
+package flow
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of entries kept by the App's default
+// MemoryCache.
+const DefaultCacheSize = 1024
+
+// Cache stores values by key with an optional time to live. Implementations
+// must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, if present and not expired.
+	Get(key string) (interface{}, bool)
+	// Set stores value under key for ttl (no expiry when ttl <= 0).
+	Set(key string, value interface{}, ttl time.Duration)
+	// Delete removes key.
+	Delete(key string)
+	// GetOrSet returns the value under key, computing it with fn and
+	// storing it for ttl when missing. Concurrent callers for the same key
+	// share a single fn call. Errors from fn are returned and not cached.
+	GetOrSet(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error)
+}
+
+// MemoryCache is an in-memory Cache holding at most a fixed number of
+// entries; when full the least recently used entry is evicted. Expired
+// entries are dropped when read or evicted.
+type MemoryCache struct {
+	mu       sync.Mutex
+	max      int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+	inflight map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time // zero means no expiry
+}
+
+// cacheCall is a GetOrSet computation shared by concurrent callers.
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxEntries entries
+// (DefaultCacheSize when maxEntries <= 0).
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheSize
+	}
+	return &MemoryCache{
+		max:      maxEntries,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+		inflight: map[string]*cacheCall{},
+	}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key, time.Now())
+}
+
+// get returns the live entry for key, marking it recently used. c.mu must
+// be held.
+func (c *MemoryCache) get(key string, now time.Time) (interface{}, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expires.IsZero() && now.After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+}
+
+// set stores value under key, evicting the least recently used entry when
+// the cache is full. c.mu must be held.
+func (c *MemoryCache) set(key string, value interface{}, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	for c.order.Len() >= c.max {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+// Len returns the number of stored entries, including expired ones not yet
+// dropped.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// GetOrSet implements Cache.
+func (c *MemoryCache) GetOrSet(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if v, ok := c.get(key, time.Now()); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	// reported to waiters (and not cached) if fn panics
+	call := &cacheCall{done: make(chan struct{}), err: fmt.Errorf("cache %s: computation panicked", key)}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.set(key, call.value, ttl)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+	return call.value, call.err
+}
@@ -0,0 +1,118 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_TTL(t *testing.T) {
+	c := NewMemoryCache(0)
+	c.Set("short", "a", 20*time.Millisecond)
+	c.Set("forever", "b", 0)
+
+	if v, ok := c.Get("short"); !ok || v != "a" {
+		t.Fatalf("Get(short) = %v, %v", v, ok)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Fatalf("expected short to expire")
+	}
+	if v, ok := c.Get("forever"); !ok || v != "b" {
+		t.Fatalf("Get(forever) = %v, %v", v, ok)
+	}
+	c.Delete("forever")
+	if _, ok := c.Get("forever"); ok {
+		t.Fatalf("expected forever to be deleted")
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Get("a") // b is now the least recently used
+	c.Set("c", 3, 0)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Fatalf("expected %s to be kept", k)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestMemoryCache_GetOrSetOnce(t *testing.T) {
+	c := NewMemoryCache(0)
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "page", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.GetOrSet("home", time.Minute, fn)
+			if err != nil {
+				t.Errorf("GetOrSet: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fn called %d times, want 1", n)
+	}
+	for i, v := range results {
+		if v != "page" {
+			t.Fatalf("result %d = %v", i, v)
+		}
+	}
+	if v, ok := c.Get("home"); !ok || v != "page" {
+		t.Fatalf("value not cached: %v %v", v, ok)
+	}
+}
+
+func TestMemoryCache_GetOrSetErrorNotCached(t *testing.T) {
+	c := NewMemoryCache(0)
+	boom := errors.New("boom")
+	if _, err := c.GetOrSet("k", time.Minute, func() (interface{}, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	v, err := c.GetOrSet("k", time.Minute, func() (interface{}, error) { return 42, nil })
+	if err != nil || v != 42 {
+		t.Fatalf("retry = %v, %v", v, err)
+	}
+}
+
+func TestContext_Cache(t *testing.T) {
+	app := New("testapp")
+	ctx := NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	ctx.Cache().Set("k", "v", 0)
+	if v, ok := app.Cache.Get("k"); !ok || v != "v" {
+		t.Fatalf("App.Cache.Get = %v, %v", v, ok)
+	}
+
+	custom := NewMemoryCache(8)
+	app = New("testapp", WithCache(custom))
+	if NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)).Cache() != Cache(custom) {
+		t.Fatalf("WithCache not used")
+	}
+}
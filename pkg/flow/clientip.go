@@ -0,0 +1,104 @@
+// Package flow: client IP addresses behind proxies.
+//
+// Behind a load balancer RemoteAddr is the balancer's address and the
+// client's is in X-Forwarded-For or X-Real-IP. Those headers are only
+// believed when the request comes from a trusted proxy, since any client
+// can send them:
+//
+//	app := flow.New("shop", flow.WithTrustedProxies("10.0.0.0/8"))
+//	...
+//	ip := ctx.ClientIP()
+package flow
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// SetTrustedProxies replaces the addresses whose X-Forwarded-For and
+// X-Real-IP headers ClientIP honours. Each entry is a CIDR ("10.0.0.0/8")
+// or a single IP. With no trusted proxies the headers are ignored.
+func (a *App) SetTrustedProxies(cidrs ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return fmt.Errorf("trusted proxies: %w", err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return fmt.Errorf("trusted proxies: %w", err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	a.trustedProxies = prefixes
+	return nil
+}
+
+// trustsProxy reports whether addr is one of the App's trusted proxies.
+func (a *App) trustsProxy(addr netip.Addr) bool {
+	if a == nil {
+		return false
+	}
+	for _, p := range a.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that made the request. When
+// the request comes from a trusted proxy (see WithTrustedProxies),
+// X-Forwarded-For is walked from right to left, skipping trusted proxies,
+// and the first untrusted entry is the client: entries further left were
+// supplied by the client and may be spoofed. When every entry is trusted or
+// one cannot be parsed, X-Real-IP is used; otherwise, and when neither
+// header helps, it is the host part of RemoteAddr.
+func (c *Context) ClientIP() string {
+	remote := c.R.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !c.App.trustsProxy(addr.Unmap()) {
+		return remote
+	}
+	var entries []string
+	for _, h := range c.R.Header.Values("X-Forwarded-For") {
+		entries = append(entries, strings.Split(h, ",")...)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip, ok := parseForwardedAddr(entries[i])
+		if !ok {
+			break
+		}
+		if !c.App.trustsProxy(ip) {
+			return ip.String()
+		}
+	}
+	if ip, ok := parseForwardedAddr(c.R.Header.Get("X-Real-IP")); ok {
+		return ip.String()
+	}
+	return addr.Unmap().String()
+}
+
+// parseForwardedAddr parses a forwarded address, with or without a port.
+func parseForwardedAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
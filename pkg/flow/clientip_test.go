@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_ClientIP(t *testing.T) {
+	app := New("ip-test", WithTrustedProxies("10.0.0.0/8", "192.0.2.1"))
+
+	cases := []struct {
+		name, remote string
+		headers      map[string]string
+		want         string
+	}{
+		{"remote addr without proxy", "203.0.113.9:5123", nil, "203.0.113.9"},
+		{"ipv6 remote addr", "[2001:db8::1]:443", nil, "2001:db8::1"},
+		{"untrusted source ignores headers", "203.0.113.9:5123",
+			map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Real-IP": "198.51.100.8"}, "203.0.113.9"},
+		{"forwarded for skips trusted proxies from the right", "10.1.2.3:80",
+			map[string]string{"X-Forwarded-For": "192.168.1.5, 198.51.100.7, 10.0.0.2", "X-Real-IP": "198.51.100.8"}, "198.51.100.7"},
+		{"spoofed forwarded for prefix is ignored", "10.1.2.3:80",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7"}, "198.51.100.7"},
+		{"untrusted private entry is the client", "10.1.2.3:80",
+			map[string]string{"X-Forwarded-For": "198.51.100.7, 172.16.0.4"}, "172.16.0.4"},
+		{"forwarded for with port", "192.0.2.1:80",
+			map[string]string{"X-Forwarded-For": "198.51.100.7:4711"}, "198.51.100.7"},
+		{"real ip when every forwarded for entry is trusted", "10.1.2.3:80",
+			map[string]string{"X-Forwarded-For": "10.0.0.4, 192.0.2.1", "X-Real-IP": "198.51.100.8"}, "198.51.100.8"},
+		{"real ip alone", "10.1.2.3:80",
+			map[string]string{"X-Real-IP": "2001:db8::2"}, "2001:db8::2"},
+		{"trusted proxy without headers", "10.1.2.3:80", nil, "10.1.2.3"},
+		{"garbage headers fall back", "10.1.2.3:80",
+			map[string]string{"X-Forwarded-For": "unknown", "X-Real-IP": "nope"}, "10.1.2.3"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		if got := NewContext(app, httptest.NewRecorder(), req).ClientIP(); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	// without trusted proxies (or an App) the headers are never believed
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:80"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	for _, a := range []*App{New("ip-test"), nil} {
+		if got := NewContext(a, httptest.NewRecorder(), req).ClientIP(); got != "10.1.2.3" {
+			t.Fatalf("expected the remote address, got %s", got)
+		}
+	}
+
+	if err := app.SetTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Fatalf("expected an error for an invalid CIDR")
+	}
+}
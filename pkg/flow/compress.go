@@ -0,0 +1,176 @@
+// Package flow: response compression.
+//
+// CompressMiddleware negotiates a content-coding from the request's
+// Accept-Encoding header (honouring q-values) and compresses the response
+// body. gzip is built in; other codings such as Brotli are plugged in by the
+// application so Flow does not force the dependency on everyone:
+//
+//	import "github.com/andybalholm/brotli"
+//
+//	br := flow.Encoder{Name: "br", New: func(w io.Writer) io.WriteCloser {
+//		return brotli.NewWriter(w)
+//	}}
+//	app.Use(flow.CompressMiddleware(br))
+package flow
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Encoder describes a content-coding that CompressMiddleware can apply.
+type Encoder struct {
+	// Name is the content-coding token, eg. "br".
+	Name string
+	// New wraps w with a compressing writer. Close must flush all data.
+	New func(w io.Writer) io.WriteCloser
+}
+
+// GzipEncoder is the built-in gzip content-coding.
+var GzipEncoder = Encoder{Name: "gzip", New: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }}
+
+// CompressMiddleware compresses responses using the best content-coding the
+// client accepts. extra encoders are preferred over gzip in the order given
+// when the client weights them equally, so passing a Brotli encoder makes
+// "Accept-Encoding: br, gzip" select Brotli. Clients that accept neither get
+// the identity encoding. Vary: Accept-Encoding is always added. Responses
+// that already carry a Content-Encoding are passed through untouched.
+func CompressMiddleware(extra ...Encoder) Middleware {
+	encoders := append(append([]Encoder(nil), extra...), GzipEncoder)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), encoders)
+			if !ok || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, enc: enc}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the encoder with the highest q-value in header.
+// Ties go to the earlier encoder in the list. A "*" entry applies to codings
+// not listed explicitly; q=0 rejects a coding.
+func negotiateEncoding(header string, encoders []Encoder) (Encoder, bool) {
+	if header == "" {
+		return Encoder{}, false
+	}
+	qs := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, found := strings.Cut(strings.TrimSpace(p), "=")
+			if found && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		qs[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	var best Encoder
+	bestQ := 0.0
+	for _, e := range encoders {
+		q, ok := qs[strings.ToLower(e.Name)]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if !ok || q <= 0 {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// compressWriter lazily starts compression on the first body write so that
+// bodyless responses (204, 304) and handlers that set their own
+// Content-Encoding are left alone.
+type compressWriter struct {
+	http.ResponseWriter
+	enc         Encoder
+	w           io.WriteCloser
+	wroteHeader bool
+	passthrough bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || code == http.StatusNoContent || code == http.StatusNotModified || code < 200 {
+		cw.passthrough = true
+	} else {
+		h.Set("Content-Encoding", cw.enc.Name)
+		h.Del("Content-Length")
+		cw.w = cw.enc.New(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		// net/http does not sniff once Content-Encoding is set, so sniff
+		// the uncompressed bytes here
+		if h := cw.Header(); h.Get("Content-Type") == "" && len(b) > 0 {
+			h.Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.w.Write(b)
+}
+
+// Close flushes and closes the compressing writer, if one was started.
+func (cw *compressWriter) Close() error {
+	if cw.w == nil {
+		return nil
+	}
+	return cw.w.Close()
+}
+
+// Flush flushes buffered compressed data to the client when both the
+// encoder and the underlying writer support it. Flushing before the first
+// Write commits a 200 status with the Content-Encoding header.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
+
+// Hijack lets protocols such as websockets take over the connection.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
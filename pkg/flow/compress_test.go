@@ -0,0 +1,150 @@
+package flow
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeBrotli stands in for a real Brotli encoder; the middleware only cares
+// about the coding name and the writer it returns.
+var fakeBrotli = Encoder{Name: "br", New: func(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.BestSpeed)
+	return fw
+}}
+
+func TestCompressMiddleware_Negotiation(t *testing.T) {
+	body := "hello compressed world"
+	h := CompressMiddleware(fakeBrotli)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"br, gzip", "br"},
+		{"gzip", "gzip"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"identity", ""},
+		{"", ""},
+		{"*", "br"},
+		{"*, br;q=0", "gzip"},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept-Encoding", tc.accept)
+		}
+		h.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Content-Encoding"); got != tc.want {
+			t.Fatalf("Accept-Encoding %q: expected encoding %q, got %q", tc.accept, tc.want, got)
+		}
+		if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Fatalf("expected Vary: Accept-Encoding, got %q", got)
+		}
+
+		var r io.Reader = rr.Body
+		switch tc.want {
+		case "gzip":
+			gr, err := gzip.NewReader(rr.Body)
+			if err != nil {
+				t.Fatalf("gzip reader: %v", err)
+			}
+			r = gr
+		case "br":
+			r = flate.NewReader(rr.Body)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		if string(got) != body {
+			t.Fatalf("Accept-Encoding %q: unexpected body %q", tc.accept, got)
+		}
+	}
+}
+
+func TestCompressMiddleware_NoContentUntouched(t *testing.T) {
+	h := CompressMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rr, req)
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.Len() != 0 {
+		t.Fatalf("expected no encoding for 204, got %q with %d bytes", rr.Header().Get("Content-Encoding"), rr.Body.Len())
+	}
+}
+
+func TestCompressMiddleware_FlushBeforeWrite(t *testing.T) {
+	h := CompressMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "event: ping\n\n")
+	}))
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !rr.Flushed {
+		t.Fatalf("expected flushed 200, got %d (flushed %v)", rr.Code, rr.Flushed)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip, got %q", got)
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != "event: ping\n\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestCompressMiddleware_ResponseController(t *testing.T) {
+	h := CompressMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Errorf("set write deadline: %v", err)
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestCompressMiddleware_SniffsContentType(t *testing.T) {
+	srv := httptest.NewServer(CompressMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>hi</body></html>"))
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip, got %q", got)
+	}
+	if got := res.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("expected sniffed text/html, got %q", got)
+	}
+}
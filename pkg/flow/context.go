@@ -13,16 +13,27 @@
 //   - Rendering helpers return errors so controller code can decide how to
 //     handle failures (log, render an error page, etc.).
 //
-// TODO: add helper for rendering layouts, template caching, and streaming
-// responses when those features are required.
+// TODO: add helper for rendering layouts and template caching when those
+// features are required.
 package flow
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	routerpkg "github.com/dministrator/flow/internal/router"
 )
@@ -45,12 +56,48 @@ type Context struct {
 	// status stores the last status set via Status or one of the render
 	// helpers. Zero means unset; helper methods will set sensible defaults.
 	status int
+
+	// renderStatus is the status the next template render writes instead
+	// of 200 (see RenderError). It only applies while status is unset.
+	renderStatus int
+
+	// created is when the Context was constructed; StartTime falls back to
+	// it when the request did not pass through an App.
+	created time.Time
+
+	// values is the per-request store behind Set and Get, looked up or
+	// created on first use.
+	values *requestValues
 }
 
 // NewContext constructs a Context. App may be nil for tests or simple
 // handlers.
 func NewContext(app *App, w http.ResponseWriter, r *http.Request) *Context {
-	return &Context{App: app, W: w, R: r}
+	return &Context{App: app, W: w, R: r, created: time.Now()}
+}
+
+// StartTime returns when the App began handling the request. If the request
+// was not served through an App (eg. a router used directly in tests) it is
+// the time the Context was created.
+func (c *Context) StartTime() time.Time {
+	if t, ok := StartTimeFromContext(c.R.Context()); ok {
+		return t
+	}
+	return c.created
+}
+
+// Elapsed returns the time spent handling the request so far.
+func (c *Context) Elapsed() time.Duration {
+	return time.Since(c.StartTime())
+}
+
+// WithParams returns a copy of ctx carrying params as route parameters,
+// exactly as the router would set them. It allows actions that call
+// Context.Param to be exercised directly, without routing:
+//
+//	req = req.WithContext(flow.WithParams(req.Context(), map[string]string{"id": "7"}))
+func WithParams(ctx context.Context, params map[string]string) context.Context {
+	return routerpkg.WithParams(ctx, params)
 }
 
 // Params returns the path parameters extracted by the router for this request.
@@ -59,16 +106,79 @@ func (c *Context) Params() map[string]string {
 	return routerpkg.ParamsFromContext(c.R.Context())
 }
 
+// RouteName returns the name of the matched route (eg. "users_show" for
+// Resources routes), or "" for unnamed routes.
+func (c *Context) RouteName() string {
+	return routerpkg.RouteNameFromContext(c.R.Context())
+}
+
 // Param returns the named path parameter or an empty string if missing.
 func (c *Context) Param(name string) string {
 	return routerpkg.Param(c.R, name)
 }
 
+// RequireParam reports whether the named path parameter is present. When
+// it is missing or empty RequireParam writes a 400 response and returns
+// false so callers can simply return.
+func (c *Context) RequireParam(name string) bool {
+	if c.Param(name) != "" {
+		return true
+	}
+	c.Error(http.StatusBadRequest, fmt.Sprintf("missing path parameter %q", name))
+	return false
+}
+
 // SetHeader sets a header on the response.
 func (c *Context) SetHeader(key, value string) {
 	c.W.Header().Set(key, value)
 }
 
+// SetHeaders sets several response headers at once.
+func (c *Context) SetHeaders(headers map[string]string) {
+	h := c.W.Header()
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+}
+
+// AddVary appends fields to the response's Vary header, skipping any that
+// are already present (compared case-insensitively). A Vary of "*" already
+// covers every field and is left untouched.
+func (c *Context) AddVary(fields ...string) {
+	h := c.W.Header()
+	var current []string
+	seen := map[string]bool{}
+	for _, line := range h.Values("Vary") {
+		for _, f := range strings.Split(line, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			if f == "*" {
+				return
+			}
+			key := strings.ToLower(f)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			current = append(current, f)
+		}
+	}
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		key := strings.ToLower(f)
+		if f == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		current = append(current, f)
+	}
+	if len(current) > 0 {
+		h.Set("Vary", strings.Join(current, ", "))
+	}
+}
+
 // Status sets the HTTP status code for the response. It immediately writes
 // the header so subsequent writes will use the status. Calling Status more
 // than once is allowed; the first call wins from the net/http perspective.
@@ -77,10 +187,50 @@ func (c *Context) Status(code int) {
 	c.W.WriteHeader(code)
 }
 
+// Default content types for JSON responses. They can be overridden per App
+// with WithJSONContentType and WithJSONErrorContentType.
+const (
+	DefaultJSONContentType    = "application/json; charset=utf-8"
+	DefaultProblemContentType = "application/problem+json"
+)
+
 // JSON writes v as a JSON response with the provided status code.
-// It sets Content-Type to application/json; charset=utf-8.
+// It sets Content-Type to application/json; charset=utf-8 unless the App
+// configures a different type via WithJSONContentType.
 func (c *Context) JSON(status int, v interface{}) error {
-	c.SetHeader("Content-Type", "application/json; charset=utf-8")
+	ct := DefaultJSONContentType
+	if c.App != nil && c.App.jsonContentType != "" {
+		ct = c.App.jsonContentType
+	}
+	return c.writeJSON(ct, status, v)
+}
+
+// Problem is an RFC 7807 problem details document written by JSONError.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// JSONError writes an RFC 7807 problem envelope for status with detail as
+// the human-readable explanation. Content-Type defaults to
+// application/problem+json and can be changed via WithJSONErrorContentType.
+func (c *Context) JSONError(status int, detail string) error {
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	ct := DefaultProblemContentType
+	if c.App != nil && c.App.problemContentType != "" {
+		ct = c.App.problemContentType
+	}
+	p := Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+	return c.writeJSON(ct, status, p)
+}
+
+// writeJSON encodes v with the given content type and status.
+func (c *Context) writeJSON(contentType string, status int, v interface{}) error {
+	c.SetHeader("Content-Type", contentType)
 	if status == 0 {
 		status = http.StatusOK
 	}
@@ -94,38 +244,178 @@ func (c *Context) JSON(status int, v interface{}) error {
 	return nil
 }
 
+// PageParams reads the 1-based "page" and "per_page" query parameters for
+// Paginate. Missing or invalid values fall back to the first page and
+// DefaultPerPage; per_page is capped at MaxPerPage.
+func (c *Context) PageParams() (page, perPage int) {
+	q := c.R.URL.Query()
+	page, _ = strconv.Atoi(q.Get("page"))
+	perPage, _ = strconv.Atoi(q.Get("per_page"))
+	return clampPage(page, perPage)
+}
+
 // RenderTemplate executes the provided template. The caller must supply a
 // parsed *template.Template (template caching is outside Context's
 // responsibility) and the name of the template to execute.
+//
+// The template is executed into a buffer and only written once it completes,
+// trading memory for the whole page against never emitting a partial one.
+// If the request context is cancelled (client gone, deadline exceeded)
+// before or during execution nothing is written and the returned error
+// wraps the context error, so errors.Is(err, context.Canceled) works.
 func (c *Context) RenderTemplate(t *template.Template, name string, data interface{}) error {
 	if t == nil {
 		return fmt.Errorf("render template: template is nil")
 	}
-	c.SetHeader("Content-Type", "text/html; charset=utf-8")
-	// default to 200 OK if not previously set
-	if c.status == 0 {
-		c.Status(http.StatusOK)
+	if err := c.requestErr(); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	if err := c.requestErr(); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	c.writeHTMLHeader()
+	if _, err := buf.WriteTo(c.W); err != nil {
+		return fmt.Errorf("render template: %w", err)
 	}
+	return nil
+}
+
+// streamTemplate executes t straight into the ResponseWriter. It is used by
+// unbuffered views; on a template error the response may be partial.
+func (c *Context) streamTemplate(t *template.Template, name string, data interface{}) error {
+	if err := c.requestErr(); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	c.writeHTMLHeader()
 	if err := t.ExecuteTemplate(c.W, name, data); err != nil {
 		return fmt.Errorf("render template: %w", err)
 	}
 	return nil
 }
 
-// Redirect sends an HTTP redirect to the client.
+// writeHTMLHeader sets the HTML content type and writes the status once:
+// the RenderError status if one is pending, otherwise 200 OK. A status
+// already written via Status is left alone.
+func (c *Context) writeHTMLHeader() {
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	if c.status != 0 {
+		return
+	}
+	if c.renderStatus != 0 {
+		c.Status(c.renderStatus)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// requestErr returns the request context's error, if any.
+func (c *Context) requestErr() error {
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Err()
+}
+
+// Redirect sends an HTTP redirect to the client. A zero code means 303 See
+// Other after an unsafe request (eg. the POST of a form, so the browser
+// follows with a GET) and 302 Found otherwise. The session, including a
+// flash added just before, is saved on the response first. If the status
+// was already written the redirect cannot be sent; it is logged and
+// skipped.
 func (c *Context) Redirect(urlStr string, code int) {
+	if c.status != 0 {
+		c.Logger().Printf("redirect to %s ignored: status %d already written", urlStr, c.status)
+		return
+	}
 	if code == 0 {
 		code = http.StatusFound
+		if !isSafeMethod(c.R.Method) {
+			code = http.StatusSeeOther
+		}
 	}
+	if s := c.Session(); s != nil {
+		if err := s.Save(); err != nil {
+			c.Logger().Printf("redirect: save session: %v", err)
+		}
+	}
+	c.status = code
 	http.Redirect(c.W, c.R, urlStr, code)
 }
 
+// ErrFileNotFound is returned by File when the path does not name a
+// readable regular file or tries to escape its directory with "..".
+var ErrFileNotFound = errors.New("flow: file not found")
+
+// File serves filePath inline (without Content-Disposition: attachment),
+// eg. to show a generated PDF in the browser. The Content-Type is derived
+// from the extension (or content sniffing) and Range/conditional requests
+// are honoured via http.ServeContent. Paths containing ".." segments are
+// rejected, so a file name taken from the request cannot escape its
+// directory. Missing files, directories and rejected paths respond 404 and
+// return an error wrapping ErrFileNotFound.
+func (c *Context) File(filePath string) error {
+	return c.serveFile(filePath, "")
+}
+
+// Attachment serves filePath as a download named filename (the file's base
+// name when empty) with Content-Disposition: attachment. Like File it
+// answers Range and If-Range requests with 206 Partial Content, so large
+// downloads can be resumed, and rejects ".." paths with ErrFileNotFound.
+func (c *Context) Attachment(filePath, filename string) error {
+	if filename == "" {
+		filename = filepath.Base(filePath)
+	}
+	return c.serveFile(filePath, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+// serveFile serves filePath with http.ServeContent, setting
+// Content-Disposition to disposition when non-empty.
+func (c *Context) serveFile(filePath, disposition string) error {
+	notFound := func() error {
+		c.Error(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return fmt.Errorf("file %s: %w", filePath, ErrFileNotFound)
+	}
+	for _, seg := range strings.FieldsFunc(filepath.ToSlash(filePath), func(r rune) bool { return r == '/' }) {
+		if seg == ".." {
+			return notFound()
+		}
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return notFound()
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return notFound()
+	}
+	if disposition != "" {
+		c.SetHeader("Content-Disposition", disposition)
+	}
+	http.ServeContent(c.W, c.R, info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// ErrEmptyBody is returned by BindJSON and BindPatch when the request has
+// no body (or only whitespace), so a missing payload can be told apart from
+// malformed JSON. Fail responds 400.
+var ErrEmptyBody = errors.New("flow: empty request body")
+
 // BindJSON decodes the request body into dst. dst must be a pointer. This
-// helper ensures the request body is closed and returns descriptive errors.
+// helper ensures the request body is closed and returns descriptive errors;
+// an empty body yields ErrEmptyBody rather than a bare io.EOF. When dst
+// implements Validator it is validated after decoding (see ValidationError).
 func (c *Context) BindJSON(dst interface{}) error {
 	if dst == nil {
 		return fmt.Errorf("bind json: dst is nil")
 	}
+	if c.R.Body == nil {
+		return fmt.Errorf("bind json: %w", ErrEmptyBody)
+	}
 	defer func() {
 		// best-effort close of body for servers that don't rely on it
 		io.Copy(io.Discard, c.R.Body)
@@ -133,11 +423,171 @@ func (c *Context) BindJSON(dst interface{}) error {
 	}()
 	dec := json.NewDecoder(c.R.Body)
 	if err := dec.Decode(dst); err != nil {
-		return fmt.Errorf("bind json: %w", err)
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("bind json: %w", ErrEmptyBody)
+		}
+		return fmt.Errorf("bind json: %w", bodyReadErr(err))
+	}
+	return validate(dst)
+}
+
+// BindOptionalJSON is BindJSON for endpoints whose body is optional: an
+// empty body leaves dst unchanged and returns nil. Malformed JSON is still
+// an error.
+func (c *Context) BindOptionalJSON(dst interface{}) error {
+	if err := c.BindJSON(dst); err != nil && !errors.Is(err, ErrEmptyBody) {
+		return err
 	}
 	return nil
 }
 
+// ErrBodyTooLarge is returned by RawBody when the request body exceeds the
+// requested limit, and matches the bind errors for a body cut off by
+// MaxBodyBytes (or any http.MaxBytesReader). Fail responds 413.
+var ErrBodyTooLarge = errors.New("flow: request body too large")
+
+// RawBody reads and returns the request body, up to maxBytes (no limit
+// when maxBytes <= 0). The body is then replaced with a fresh reader over
+// the captured bytes, so BindJSON and friends still work afterwards; this
+// lets webhook handlers verify a signature over the exact bytes received
+// before decoding them. A body over the limit yields ErrBodyTooLarge and is
+// left readable in full.
+func (c *Context) RawBody(maxBytes int64) ([]byte, error) {
+	if c.R == nil || c.R.Body == nil || c.R.Body == http.NoBody {
+		return nil, nil
+	}
+	orig := c.R.Body
+	var src io.Reader = orig
+	if maxBytes > 0 {
+		src = io.LimitReader(orig, maxBytes+1)
+	}
+	b, err := io.ReadAll(src)
+	if err != nil {
+		c.R.Body = readCloser{io.MultiReader(bytes.NewReader(b), orig), orig}
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if maxBytes > 0 && int64(len(b)) > maxBytes {
+		c.R.Body = readCloser{io.MultiReader(bytes.NewReader(b), orig), orig}
+		return nil, ErrBodyTooLarge
+	}
+	orig.Close()
+	c.R.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// readCloser pairs a Reader with the Closer of the body it wraps.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// IsPatch reports whether the request method is PATCH. Resources route both
+// PUT and PATCH to Update; actions use IsPatch to apply a partial update
+// (see BindPatch and UpdateColumns) instead of replacing the whole record.
+func (c *Context) IsPatch() bool {
+	return c.R.Method == http.MethodPatch
+}
+
+// WantsJSON reports whether the caller is an API client: it accepts JSON
+// (application/json or a +json type) or sent the request via XHR. Fail
+// uses it to choose between a JSON problem and an HTML error page.
+func (c *Context) WantsJSON() bool {
+	return wantsJSON(c.R)
+}
+
+// IsAjax reports whether the request carries X-Requested-With:
+// XMLHttpRequest, as set by jQuery and most XHR helpers.
+func (c *Context) IsAjax() bool {
+	return isAjax(c.R)
+}
+
+// wantsJSON reports whether r asks for JSON; see Context.WantsJSON.
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "+json") || isAjax(r)
+}
+
+// isAjax reports whether r was sent via XMLHttpRequest.
+func isAjax(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("X-Requested-With"), "XMLHttpRequest")
+}
+
+// BindPatch decodes a JSON object body into dst (a pointer to a struct,
+// typically a model already loaded from the database) and returns the
+// column names of the fields present in the body. Columns are resolved from
+// each field's bun tag, falling back to its json name. Keys that do not map
+// to a field are ignored. Pass the result to UpdateColumns so only the
+// provided fields are written. Every json-mapped column is returned,
+// including the key and timestamps of an embedded Model (id, created_at,
+// updated_at, deleted_at), so callers must drop the columns clients may not
+// write before updating.
+func (c *Context) BindPatch(dst interface{}) ([]string, error) {
+	if dst == nil {
+		return nil, fmt.Errorf("bind patch: dst is nil")
+	}
+	defer c.R.Body.Close()
+	body, err := io.ReadAll(c.R.Body)
+	if err != nil {
+		return nil, fmt.Errorf("bind patch: %w", bodyReadErr(err))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, fmt.Errorf("bind patch: %w", ErrEmptyBody)
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(body, &present); err != nil {
+		return nil, fmt.Errorf("bind patch: %w", err)
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return nil, fmt.Errorf("bind patch: %w", err)
+	}
+	cols := jsonToColumns(reflect.TypeOf(dst))
+	var out []string
+	for k := range present {
+		if col, ok := cols[k]; ok {
+			out = append(out, col)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// jsonToColumns maps json field names to column names for struct type t
+// (or a pointer to it), descending into embedded structs.
+func jsonToColumns(t reflect.Type) map[string]string {
+	out := map[string]string{}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return out
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && jsonName == "" {
+			for k, v := range jsonToColumns(f.Type) {
+				out[k] = v
+			}
+			continue
+		}
+		if !f.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		col := strings.Split(f.Tag.Get("bun"), ",")[0]
+		if col == "" || col == "-" {
+			col = strings.Split(f.Tag.Get("db"), ",")[0]
+		}
+		if col == "" || col == "-" {
+			col = jsonName
+		}
+		out[jsonName] = col
+	}
+	return out
+}
+
 // FormValue is a small helper to retrieve form values (POST/PUT). It calls
 // ParseForm if necessary.
 func (c *Context) FormValue(key string) string {
@@ -146,15 +596,117 @@ func (c *Context) FormValue(key string) string {
 	return c.R.FormValue(key)
 }
 
+// Query returns the first value of the URL query parameter name, or "".
+// Unlike FormValue it never reads the request body.
+func (c *Context) Query(name string) string {
+	return c.R.URL.Query().Get(name)
+}
+
+// QueryDefault returns the query parameter name, or def when it is missing
+// or empty.
+func (c *Context) QueryDefault(name, def string) string {
+	if v := c.Query(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// QueryInt returns the query parameter name as an int, or def when it is
+// missing or not an integer.
+func (c *Context) QueryInt(name string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(c.Query(name)))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// QueryBool returns the query parameter name parsed with strconv.ParseBool
+// (1, t, true, 0, f, false, ...), or def when it is missing or invalid.
+func (c *Context) QueryBool(name string, def bool) bool {
+	b, err := strconv.ParseBool(strings.TrimSpace(c.Query(name)))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
 // Render is a convenience helper that uses the App's ViewManager to render
-// the named template. It returns an error if views are not configured.
+// the named template. It returns ErrViewsNotConfigured when the App has no
+// ViewManager instead of panicking.
 func (c *Context) Render(name string, data interface{}) error {
 	if c.App == nil || c.App.Views == nil {
-		return fmt.Errorf("render: views not configured")
+		return ErrViewsNotConfigured
 	}
 	return c.App.Views.Render(name, data, c)
 }
 
+// RenderError renders the named template with the given status, eg.
+// RenderError(http.StatusUnprocessableEntity, "posts/new", data) to show a
+// form again after validation failed. The status is written exactly once,
+// together with the rendered page, so a failing template can still be
+// answered with a 500 when views are buffered. If a status was already
+// written via Status it is kept.
+func (c *Context) RenderError(status int, name string, data interface{}) error {
+	c.renderStatus = status
+	defer func() { c.renderStatus = 0 }()
+	return c.Render(name, data)
+}
+
+// RenderFragment renders only the named view's content block, eg. for HTMX
+// swaps or Turbo frames; see ViewManager.RenderFragment.
+func (c *Context) RenderFragment(name string, data interface{}) error {
+	if c.App == nil || c.App.Views == nil {
+		return ErrViewsNotConfigured
+	}
+	return c.App.Views.RenderFragment(name, data, c)
+}
+
+// Cache returns the App's Cache, or nil when the Context has no App.
+func (c *Context) Cache() Cache {
+	if c.App == nil {
+		return nil
+	}
+	return c.App.Cache
+}
+
+// RenderPartial renders a single partial (eg. "forms/input") without a
+// layout or view; see ViewManager.RenderPartial.
+func (c *Context) RenderPartial(name string, data interface{}) error {
+	if c.App == nil || c.App.Views == nil {
+		return ErrViewsNotConfigured
+	}
+	return c.App.Views.RenderPartial(name, data, c)
+}
+
+// Logger returns a logger for the current request. When LoggingMiddleware
+// is installed it carries the request ID, method and path; otherwise one is
+// derived from the App logger (or the standard logger when App is nil).
+func (c *Context) Logger() Logger {
+	if l := LoggerFromContext(c.R.Context()); l != nil {
+		return l
+	}
+	var base Logger
+	if c.App != nil {
+		base = c.App.logger
+	}
+	return WithFields(base, requestFields(c.R))
+}
+
+// TraceHeaders returns the correlation headers captured by TraceMiddleware
+// for copying onto outbound requests:
+//
+//	for k, vs := range ctx.TraceHeaders() {
+//		for _, v := range vs {
+//			outReq.Header.Add(k, v)
+//		}
+//	}
+//
+// It returns an empty header when the middleware is not installed.
+func (c *Context) TraceHeaders() http.Header {
+	return TraceHeadersFromContext(c.R.Context())
+}
+
 // Session returns the session store for the current request, or nil if
 // sessions are not configured. Use Session().Get/Set/Delete to manage
 // session data. Session writes a cookie on Set/Delete/Save.
@@ -177,6 +729,10 @@ func (c *Context) AddFlash(kind, msg string) error {
 	}
 	var list []map[string]string
 	if v, ok := s.Get("_flash"); ok {
+		// added earlier in this request, not yet decoded from the cookie
+		if added, ok := v.([]map[string]string); ok {
+			list = append(list, added...)
+		}
 		if arr, ok := v.([]interface{}); ok {
 			for _, it := range arr {
 				if m, ok := it.(map[string]interface{}); ok {
@@ -237,5 +793,5 @@ func (c *Context) Error(status int, msg string) {
 	_, _ = c.W.Write([]byte(msg))
 }
 
-// TODO: add helpers for file uploads, streaming responses, template caching,
-// secure cookie helpers, and content negotiation as the framework evolves.
+// TODO: add helpers for template caching and secure cookies as the
+// framework evolves.
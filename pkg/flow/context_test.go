@@ -0,0 +1,412 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithParams_ActionReadsParam(t *testing.T) {
+	app := New("testapp")
+	users := NewUsersController(app)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users/99", nil)
+	req = req.WithContext(WithParams(req.Context(), map[string]string{"id": "99"}))
+	users.Show(NewContext(app, rr, req))
+
+	if got := rr.Body.String(); got != "99" {
+		t.Fatalf("expected action to read id 99, got %q", got)
+	}
+}
+
+func TestContext_JSONContentTypeConfigurable(t *testing.T) {
+	// default
+	rr := httptest.NewRecorder()
+	_ = NewContext(New("t"), rr, httptest.NewRequest("GET", "/", nil)).JSON(200, map[string]int{"a": 1})
+	if ct := rr.Header().Get("Content-Type"); ct != DefaultJSONContentType {
+		t.Fatalf("expected default content type, got %q", ct)
+	}
+
+	app := New("t", WithJSONContentType("application/json"), WithJSONErrorContentType("application/json"))
+	rr = httptest.NewRecorder()
+	_ = NewContext(app, rr, httptest.NewRequest("GET", "/", nil)).JSON(200, map[string]int{"a": 1})
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected configured content type, got %q", ct)
+	}
+
+	rr = httptest.NewRecorder()
+	_ = NewContext(app, rr, httptest.NewRequest("GET", "/", nil)).JSONError(404, "no such user")
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected configured error content type, got %q", ct)
+	}
+}
+
+func TestContext_JSONErrorProblemEnvelope(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/", nil))
+	if err := ctx.JSONError(404, "no such user"); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != 404 {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != DefaultProblemContentType {
+		t.Fatalf("expected problem content type, got %q", ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != 404 || p.Title != "Not Found" || p.Detail != "no such user" {
+		t.Fatalf("unexpected problem body: %+v", p)
+	}
+}
+
+func TestContext_AddVaryDeduplicates(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/", nil))
+	ctx.SetHeaders(map[string]string{"Vary": "Accept-Encoding", "Cache-Control": "no-cache"})
+
+	ctx.AddVary("Accept")
+	ctx.AddVary("Accept", "accept-encoding")
+
+	if got := rr.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept-Encoding, Accept" {
+		t.Fatalf("unexpected Vary header: %q", got)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Fatalf("expected SetHeaders to set Cache-Control, got %q", got)
+	}
+}
+
+func TestContext_ElapsedIncreases(t *testing.T) {
+	app := New("timing")
+	var start time.Time
+	var first, second time.Duration
+	app.SetRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(app, w, r)
+		start = ctx.StartTime()
+		first = ctx.Elapsed()
+		time.Sleep(5 * time.Millisecond)
+		second = ctx.Elapsed()
+	}))
+
+	before := time.Now()
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if start.Before(before) {
+		t.Fatalf("expected start time to be set by the App, got %v (before %v)", start, before)
+	}
+	if first < 0 {
+		t.Fatalf("expected non-negative elapsed, got %v", first)
+	}
+	if second <= first || second < 5*time.Millisecond {
+		t.Fatalf("expected elapsed to increase: first=%v second=%v", first, second)
+	}
+}
+
+func TestFile_ServesInline(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(p, []byte("%PDF-1.4 fake"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := New("testapp")
+
+	rr := httptest.NewRecorder()
+	if err := NewContext(app, rr, httptest.NewRequest("GET", "/report", nil)).File(p); err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Fatalf("expected application/pdf, got %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "" {
+		t.Fatalf("inline file should not set Content-Disposition, got %q", cd)
+	}
+	if rr.Body.String() != "%PDF-1.4 fake" {
+		t.Fatalf("unexpected body %q", rr.Body.String())
+	}
+
+	for _, bad := range []string{filepath.Join(dir, "missing.pdf"), dir, dir + "/../" + filepath.Base(dir) + "/report.pdf"} {
+		rr := httptest.NewRecorder()
+		err := NewContext(app, rr, httptest.NewRequest("GET", "/report", nil)).File(bad)
+		if !errors.Is(err, ErrFileNotFound) || rr.Code != http.StatusNotFound {
+			t.Fatalf("File(%q): expected 404 and ErrFileNotFound, got %d %v", bad, rr.Code, err)
+		}
+	}
+}
+
+func TestFile_RangeRequests(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "video.bin")
+	if err := os.WriteFile(p, []byte("0123456789abcdef"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := New("testapp")
+
+	for name, serve := range map[string]func(*Context) error{
+		"File":       func(c *Context) error { return c.File(p) },
+		"Attachment": func(c *Context) error { return c.Attachment(p, "clip.bin") },
+	} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/video", nil)
+		req.Header.Set("Range", "bytes=4-9")
+		if err := serve(NewContext(app, rr, req)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("%s: expected 206, got %d", name, rr.Code)
+		}
+		if cr := rr.Header().Get("Content-Range"); cr != "bytes 4-9/16" {
+			t.Fatalf("%s: Content-Range = %q", name, cr)
+		}
+		if rr.Body.String() != "456789" {
+			t.Fatalf("%s: partial body = %q", name, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	if err := NewContext(app, rr, httptest.NewRequest("GET", "/video", nil)).Attachment(p, ""); err != nil {
+		t.Fatalf("Attachment: %v", err)
+	}
+	if ar := rr.Header().Get("Accept-Ranges"); ar != "bytes" {
+		t.Fatalf("Accept-Ranges = %q", ar)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "attachment; filename=video.bin" {
+		t.Fatalf("Content-Disposition = %q", cd)
+	}
+	if rr.Body.String() != "0123456789abcdef" {
+		t.Fatalf("full body = %q", rr.Body.String())
+	}
+}
+
+func TestRenderTemplate_CancelledRequest(t *testing.T) {
+	tpl := template.Must(template.New("page").Parse("<html>{{.}}</html>"))
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	ctx := NewContext(nil, rr, req)
+
+	err := ctx.RenderTemplate(tpl, "page", "hi")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if rr.Body.Len() != 0 {
+		t.Fatalf("expected no body, got %q", rr.Body.String())
+	}
+	if ctx.status != 0 {
+		t.Fatalf("expected no status written, got %d", ctx.status)
+	}
+}
+
+func TestContext_RawBodyThenBindJSON(t *testing.T) {
+	body := `{"event":"push","id":7}`
+	ctx := NewContext(nil, httptest.NewRecorder(), httptest.NewRequest("POST", "/hook", strings.NewReader(body)))
+
+	raw, err := ctx.RawBody(1 << 10)
+	if err != nil {
+		t.Fatalf("raw body: %v", err)
+	}
+	if string(raw) != body {
+		t.Fatalf("unexpected raw body %q", raw)
+	}
+	var got struct {
+		Event string `json:"event"`
+		ID    int    `json:"id"`
+	}
+	if err := ctx.BindJSON(&got); err != nil {
+		t.Fatalf("bind json after raw body: %v", err)
+	}
+	if got.Event != "push" || got.ID != 7 {
+		t.Fatalf("unexpected bound value %+v", got)
+	}
+}
+
+func TestContext_RawBodyTooLarge(t *testing.T) {
+	ctx := NewContext(nil, httptest.NewRecorder(), httptest.NewRequest("POST", "/hook", strings.NewReader("0123456789")))
+	if _, err := ctx.RawBody(4); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+	// the body is still readable in full
+	if raw, err := ctx.RawBody(0); err != nil || string(raw) != "0123456789" {
+		t.Fatalf("expected full body after limit error, got %q, %v", raw, err)
+	}
+}
+
+func TestContext_WantsJSONAndIsAjax(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		value    string
+		wantJSON bool
+		wantAjax bool
+	}{
+		{"accept json", "Accept", "application/json", true, false},
+		{"accept problem json", "Accept", "application/problem+json", true, false},
+		{"xhr", "X-Requested-With", "XMLHttpRequest", true, true},
+		{"browser", "Accept", "text/html,application/xhtml+xml", false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(tc.header, tc.value)
+			ctx := NewContext(nil, httptest.NewRecorder(), req)
+			if got := ctx.WantsJSON(); got != tc.wantJSON {
+				t.Fatalf("WantsJSON = %v, want %v", got, tc.wantJSON)
+			}
+			if got := ctx.IsAjax(); got != tc.wantAjax {
+				t.Fatalf("IsAjax = %v, want %v", got, tc.wantAjax)
+			}
+		})
+	}
+}
+
+func TestContext_BindJSONEmptyBody(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+	bind := func(body string, optional bool) (payload, error) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		ctx := NewContext(nil, httptest.NewRecorder(), req)
+		p := payload{Name: "unchanged"}
+		if optional {
+			return p, ctx.BindOptionalJSON(&p)
+		}
+		return p, ctx.BindJSON(&p)
+	}
+
+	for _, body := range []string{"", "  \n"} {
+		if _, err := bind(body, false); !errors.Is(err, ErrEmptyBody) {
+			t.Fatalf("body %q: expected ErrEmptyBody, got %v", body, err)
+		}
+	}
+	if _, err := bind(`{"name":`, false); err == nil || errors.Is(err, ErrEmptyBody) {
+		t.Fatalf("expected a malformed JSON error distinct from ErrEmptyBody, got %v", err)
+	}
+
+	p, err := bind("", true)
+	if err != nil || p.Name != "unchanged" {
+		t.Fatalf("optional bind of empty body: got %+v, %v", p, err)
+	}
+	if _, err := bind("{bad", true); err == nil {
+		t.Fatalf("optional bind should still reject malformed JSON")
+	}
+	if p, err := bind(`{"name":"x"}`, true); err != nil || p.Name != "x" {
+		t.Fatalf("optional bind of a body: got %+v, %v", p, err)
+	}
+
+	if got := (*App)(nil).ErrorStatus(fmt.Errorf("bind json: %w", ErrEmptyBody)); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 for ErrEmptyBody, got %d", got)
+	}
+}
+
+func TestContext_QueryGetters(t *testing.T) {
+	req := httptest.NewRequest("GET", "/items?q=shoes&empty=&page=3&bad=x7&on=true&flag=1&nope=maybe", nil)
+	ctx := NewContext(nil, httptest.NewRecorder(), req)
+
+	if ctx.Query("q") != "shoes" || ctx.Query("missing") != "" {
+		t.Fatalf("unexpected Query results: %q, %q", ctx.Query("q"), ctx.Query("missing"))
+	}
+	strs := map[string]string{"q": "shoes", "empty": "all", "missing": "all"}
+	for name, want := range strs {
+		if got := ctx.QueryDefault(name, "all"); got != want {
+			t.Fatalf("QueryDefault(%q): expected %q, got %q", name, want, got)
+		}
+	}
+	ints := map[string]int{"page": 3, "bad": 1, "missing": 1, "empty": 1}
+	for name, want := range ints {
+		if got := ctx.QueryInt(name, 1); got != want {
+			t.Fatalf("QueryInt(%q): expected %d, got %d", name, want, got)
+		}
+	}
+	bools := map[string]bool{"on": true, "flag": true, "nope": false, "missing": false}
+	for name, want := range bools {
+		if got := ctx.QueryBool(name, false); got != want {
+			t.Fatalf("QueryBool(%q): expected %v, got %v", name, want, got)
+		}
+	}
+	if !ctx.QueryBool("nope", true) || !ctx.QueryBool("missing", true) {
+		t.Fatalf("expected the default for malformed and missing bools")
+	}
+}
+
+func TestContext_RedirectStatus(t *testing.T) {
+	cases := []struct {
+		method string
+		code   int
+		want   int
+	}{
+		{"POST", 0, http.StatusSeeOther},
+		{"DELETE", 0, http.StatusSeeOther},
+		{"GET", 0, http.StatusFound},
+		{"POST", http.StatusMovedPermanently, http.StatusMovedPermanently},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		NewContext(nil, rec, httptest.NewRequest(tc.method, "/items", nil)).Redirect("/items/1", tc.code)
+		if rec.Code != tc.want || rec.Header().Get("Location") != "/items/1" {
+			t.Fatalf("%s with code %d: expected %d to /items/1, got %d to %q", tc.method, tc.code, tc.want, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	// once a status is written the redirect is skipped
+	rec := httptest.NewRecorder()
+	ctx := NewContext(New("redirect-test", WithLogger(NopLogger())), rec, httptest.NewRequest("POST", "/items", nil))
+	ctx.Status(http.StatusAccepted)
+	ctx.Redirect("/items/1", 0)
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Location") != "" {
+		t.Fatalf("expected the earlier status to stand, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestContext_FlashSurvivesRedirect(t *testing.T) {
+	app := New("flash-test")
+	app.Use(app.Sessions.Middleware())
+	r := NewRouter(app)
+	r.Post("/items", func(c *Context) {
+		c.AddFlash("notice", "Item created")
+		c.AddFlash("info", "Check your email")
+		c.Redirect("/items", 0)
+	})
+	r.Get("/items", func(c *Context) {
+		flashes, err := c.Flashes()
+		if err != nil {
+			t.Errorf("flashes: %v", err)
+		}
+		c.JSON(http.StatusOK, flashes)
+	})
+	app.SetRouter(r)
+
+	rec := httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/items", nil))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != app.Sessions.CookieName {
+		t.Fatalf("expected a single session cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/items", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "Item created") || !strings.Contains(body, "Check your email") {
+		t.Fatalf("expected both flashes after the redirect, got %s", body)
+	}
+}
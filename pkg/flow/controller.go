@@ -47,11 +47,39 @@ func (c *Controller) Handler(action func(*Context)) http.HandlerFunc {
 // configured views directory). Returns an error if rendering fails.
 func (c *Controller) Render(ctx *Context, name string, data interface{}) error {
 	if c.App == nil || c.App.Views == nil {
-		return fmt.Errorf("controller: view manager not configured")
+		return fmt.Errorf("controller: %w", ErrViewsNotConfigured)
 	}
 	return c.App.Views.Render(name, data, ctx)
 }
 
+// RenderOrError renders the named template and, if rendering fails, logs
+// the error via the App logger and responds with a 500. Actions can call it
+// instead of handling render errors themselves.
+func (c *Controller) RenderOrError(ctx *Context, name string, data interface{}) {
+	if err := c.Render(ctx, name, data); err != nil {
+		if c.App != nil && c.App.logger != nil {
+			c.App.logger.Printf("render %s: %v", name, err)
+		}
+		ctx.Error(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// JSON writes v as a JSON response. It is a pass-through to ctx.JSON.
+func (c *Controller) JSON(ctx *Context, status int, v interface{}) error {
+	return ctx.JSON(status, v)
+}
+
+// Redirect sends an HTTP redirect. It is a pass-through to ctx.Redirect.
+func (c *Controller) Redirect(ctx *Context, urlStr string, code int) {
+	ctx.Redirect(urlStr, code)
+}
+
+// AddFlash adds a flash message to the session. It is a pass-through to
+// ctx.AddFlash.
+func (c *Controller) AddFlash(ctx *Context, kind, msg string) error {
+	return ctx.AddFlash(kind, msg)
+}
+
 // Resource defines the idiomatic controller methods for RESTful resources.
 // Application controllers implementing resourceful behavior should implement
 // these methods. This keeps controller implementations small and focused on
@@ -68,7 +96,9 @@ type Resource interface {
 
 // resourceAdapter adapts a Resource (methods that accept *flow.Context)
 // to the internal router.ResourceController which expects methods with
-// (http.ResponseWriter, *http.Request) signatures.
+// (http.ResponseWriter, *http.Request) signatures. Member actions (Show,
+// Edit, Update, Destroy) respond with 400 when the :id parameter is missing,
+// which catches routing misconfiguration before user code runs.
 type resourceAdapter struct {
 	app *App
 	r   Resource
@@ -97,21 +127,33 @@ func (a *resourceAdapter) Create(w http.ResponseWriter, req *http.Request) {
 
 func (a *resourceAdapter) Show(w http.ResponseWriter, req *http.Request) {
 	ctx := NewContext(a.app, w, req)
+	if !ctx.RequireParam("id") {
+		return
+	}
 	a.r.Show(ctx)
 }
 
 func (a *resourceAdapter) Edit(w http.ResponseWriter, req *http.Request) {
 	ctx := NewContext(a.app, w, req)
+	if !ctx.RequireParam("id") {
+		return
+	}
 	a.r.Edit(ctx)
 }
 
 func (a *resourceAdapter) Update(w http.ResponseWriter, req *http.Request) {
 	ctx := NewContext(a.app, w, req)
+	if !ctx.RequireParam("id") {
+		return
+	}
 	a.r.Update(ctx)
 }
 
 func (a *resourceAdapter) Destroy(w http.ResponseWriter, req *http.Request) {
 	ctx := NewContext(a.app, w, req)
+	if !ctx.RequireParam("id") {
+		return
+	}
 	a.r.Destroy(ctx)
 }
 
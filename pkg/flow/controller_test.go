@@ -0,0 +1,40 @@
+package flow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestController_RenderOrErrorLogsAnd500s(t *testing.T) {
+	logger, lines := NewTestLogger()
+	app := New("testapp", WithLogger(logger))
+	app.Views = NewViewManager(t.TempDir())
+	c := NewController(app)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	c.RenderOrError(c.WithContext(rr, req), "missing/view", nil)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 on render error, got %d", rr.Code)
+	}
+	if got := strings.Join(lines(), "\n"); !strings.Contains(got, "render missing/view") {
+		t.Fatalf("expected render error to be logged, got: %q", got)
+	}
+}
+
+func TestResourceAdapter_MemberActionWithoutIDIs400(t *testing.T) {
+	app := New("testapp")
+	adapter := MakeResourceAdapter(app, NewUsersController(app))
+
+	// call the member action directly, bypassing the router so no :id is set
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users/", nil)
+	adapter.Show(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for member action without id, got %d", rr.Code)
+	}
+}
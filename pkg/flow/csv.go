@@ -0,0 +1,81 @@
+// Package flow: CSV responses.
+//
+// CSV writes a complete set of records as a downloadable file; CSVStream
+// writes rows as they arrive on a channel, flushing periodically, so large
+// exports never have to be held in memory. Both quote and escape fields via
+// encoding/csv.
+package flow
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// csvFlushEvery is how many streamed rows are written between flushes.
+const csvFlushEvery = 100
+
+// CSV writes records as a CSV attachment named filename with the given
+// status. Fields containing commas, quotes or newlines are quoted.
+func (c *Context) CSV(status int, filename string, records [][]string) error {
+	c.csvHeaders(status, filename)
+	w := csv.NewWriter(c.W)
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("render csv: %w", err)
+	}
+	return nil
+}
+
+// CSVStream writes each row received from rows until the channel is closed
+// or the request is cancelled. Output is flushed to the client every few
+// rows when the ResponseWriter supports http.Flusher. Since the status is
+// sent before the first row, errors after that point can only be returned,
+// not reported to the client.
+func (c *Context) CSVStream(status int, filename string, rows <-chan []string) error {
+	c.csvHeaders(status, filename)
+	w := csv.NewWriter(c.W)
+	flusher, _ := c.W.(http.Flusher)
+	flush := func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("render csv: %w", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	done := c.R.Context().Done()
+	for n := 1; ; n++ {
+		select {
+		case <-done:
+			return fmt.Errorf("render csv: %w", c.R.Context().Err())
+		case row, ok := <-rows:
+			if !ok {
+				return flush()
+			}
+			if err := w.Write(row); err != nil {
+				return fmt.Errorf("render csv: %w", err)
+			}
+			if n%csvFlushEvery == 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// csvHeaders sets the CSV content type and attachment disposition, then
+// writes status (200 when zero).
+func (c *Context) csvHeaders(status int, filename string) {
+	c.SetHeader("Content-Type", "text/csv; charset=utf-8")
+	if filename != "" {
+		c.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+}
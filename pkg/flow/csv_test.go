@@ -0,0 +1,51 @@
+package flow
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_CSV(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/report", nil))
+
+	records := [][]string{
+		{"name", "city"},
+		{"Ada", "London, UK"},
+		{"Bob", `say "hi"`},
+	}
+	if err := ctx.CSV(200, "report.csv", records); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	want := "name,city\nAda,\"London, UK\"\nBob,\"say \"\"hi\"\"\"\n"
+	if got := rr.Body.String(); got != want {
+		t.Fatalf("unexpected csv:\n got %q\nwant %q", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != `attachment; filename=report.csv` {
+		t.Fatalf("unexpected content disposition %q", cd)
+	}
+}
+
+func TestContext_CSVStream(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/export", nil))
+
+	rows := make(chan []string)
+	go func() {
+		defer close(rows)
+		rows <- []string{"id", "note"}
+		rows <- []string{"1", "a,b"}
+	}()
+	if err := ctx.CSVStream(200, "export.csv", rows); err != nil {
+		t.Fatalf("csv stream: %v", err)
+	}
+	if got, want := rr.Body.String(), "id,note\n1,\"a,b\"\n"; got != want {
+		t.Fatalf("unexpected csv: got %q want %q", got, want)
+	}
+	if !rr.Flushed {
+		t.Fatalf("expected the stream to be flushed")
+	}
+}
@@ -0,0 +1,185 @@
+// Package flow: per-environment database configuration.
+//
+// config/database.yaml holds one block per environment, in the spirit of
+// Rails' database.yml:
+//
+//	development:
+//	  driver: sqlite
+//	  dsn: file:dev.db
+//	production:
+//	  driver: postgres
+//	  dsn: ${DATABASE_URL}
+//
+// Only this two-level form is understood (no anchors, lists or nesting).
+// Values may be quoted, "#" starts a comment, and ${VAR} references are
+// expanded from the process environment so secrets stay out of the file.
+// Only the braced form is expanded: a bare "$" (eg. in a password) is kept.
+package flow
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvVar selects the environment (development, test, production, ...).
+	EnvVar = "FLOW_ENV"
+	// DefaultEnv is used when EnvVar is unset.
+	DefaultEnv = "development"
+	// DefaultDatabaseConfigPath is where the CLI looks for database settings.
+	DefaultDatabaseConfigPath = "config/database.yaml"
+)
+
+// DatabaseConfig is the database an environment connects to.
+type DatabaseConfig struct {
+	Driver string
+	DSN    string
+}
+
+// Env returns the current environment from FLOW_ENV, defaulting to
+// development.
+func Env() string {
+	if env := strings.TrimSpace(os.Getenv(EnvVar)); env != "" {
+		return env
+	}
+	return DefaultEnv
+}
+
+// LoadDatabaseConfig reads the database settings for env (Env() when
+// empty) from the YAML file at path. Errors from reading the file wrap the
+// underlying error, so a missing file can be detected with
+// errors.Is(err, fs.ErrNotExist).
+func LoadDatabaseConfig(path, env string) (DatabaseConfig, error) {
+	if env == "" {
+		env = Env()
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return DatabaseConfig{}, fmt.Errorf("database config: %w", err)
+	}
+	envs, err := parseDatabaseYAML(b)
+	if err != nil {
+		return DatabaseConfig{}, fmt.Errorf("database config: %s: %w", path, err)
+	}
+	cfg, ok := envs[env]
+	if !ok {
+		return DatabaseConfig{}, fmt.Errorf("database config: %s: no %q environment", path, env)
+	}
+	if cfg.DSN == "" {
+		return DatabaseConfig{}, fmt.Errorf("database config: %s: %q environment has no dsn", path, env)
+	}
+	return cfg, nil
+}
+
+// parseDatabaseYAML parses the environment -> driver/dsn mapping described
+// in the file comment.
+func parseDatabaseYAML(data []byte) (map[string]DatabaseConfig, error) {
+	envs := map[string]DatabaseConfig{}
+	current := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := stripYAMLComment(sc.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key, value = strings.TrimSpace(key), unquoteYAML(strings.TrimSpace(value))
+		if line[0] != ' ' && line[0] != '\t' {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: environment %q must be followed by indented settings", n, key)
+			}
+			current = key
+			envs[current] = DatabaseConfig{}
+			continue
+		}
+		if current == "" {
+			return nil, fmt.Errorf("line %d: setting %q outside an environment", n, key)
+		}
+		cfg := envs[current]
+		switch key {
+		case "driver":
+			cfg.Driver = expandBracedEnv(value)
+		case "dsn":
+			cfg.DSN = expandBracedEnv(value)
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting %q", n, key)
+		}
+		envs[current] = cfg
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return envs, nil
+}
+
+// expandBracedEnv replaces ${NAME} references in s with the environment
+// variable's value. Unlike os.ExpandEnv it leaves "$name" and any other "$"
+// alone, so literal DSNs like postgres://u:pa$word@h/db survive.
+func expandBracedEnv(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(s[i+2:], '}')
+		if end < 0 {
+			break
+		}
+		name := s[i+2 : i+2+end]
+		b.WriteString(s[:i])
+		if isEnvName(name) {
+			b.WriteString(os.Getenv(name))
+		} else {
+			b.WriteString(s[i : i+3+end])
+		}
+		s = s[i+3+end:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// isEnvName reports whether name is a valid environment variable name.
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c != '_' && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripYAMLComment drops a "#" comment unless it appears inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteYAML removes matching single or double quotes around v.
+func unquoteYAML(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
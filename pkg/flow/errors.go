@@ -0,0 +1,104 @@
+// Package flow: error-to-status mapping.
+//
+// Actions return domain errors; Context.Fail turns them into responses.
+// The App maps errors to HTTP statuses with errors.Is, so wrapped errors
+// match too:
+//
+//	var ErrBanned = errors.New("user banned")
+//	app.RegisterErrorStatus(ErrBanned, http.StatusForbidden)
+//	...
+//	if err := svc.Post(ctx, u); err != nil {
+//		ctx.Fail(err)
+//		return
+//	}
+package flow
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ErrNotFound reports a missing record or resource. Fail responds 404.
+var ErrNotFound = errors.New("flow: not found")
+
+// ErrValidation marks invalid input; wrap it with details, eg.
+// fmt.Errorf("%w: title is required", flow.ErrValidation). Fail responds 422.
+var ErrValidation = errors.New("flow: validation failed")
+
+// errorStatus maps an error to an HTTP status.
+type errorStatus struct {
+	err    error
+	status int
+}
+
+// builtinErrorStatuses apply after App registrations.
+var builtinErrorStatuses = []errorStatus{
+	{ErrNotFound, http.StatusNotFound},
+	{sql.ErrNoRows, http.StatusNotFound},
+	{ErrFileNotFound, http.StatusNotFound},
+	{ErrValidation, http.StatusUnprocessableEntity},
+	{ErrBodyTooLarge, http.StatusRequestEntityTooLarge},
+	{ErrMissingUpload, http.StatusBadRequest},
+	{ErrEmptyUpload, http.StatusBadRequest},
+	{ErrEmptyBody, http.StatusBadRequest},
+	{ErrNotAcceptable, http.StatusNotAcceptable},
+	{ErrForbidden, http.StatusForbidden},
+	{ErrSignatureInvalid, http.StatusBadRequest},
+	{ErrSignatureExpired, http.StatusBadRequest},
+	{ErrInvalidCallback, http.StatusBadRequest},
+}
+
+// RegisterErrorStatus maps err (matched with errors.Is) to status for
+// Context.Fail. Later registrations take precedence over earlier ones and
+// over the built-in mappings.
+func (a *App) RegisterErrorStatus(err error, status int) {
+	a.errorStatuses = append(a.errorStatuses, errorStatus{err: err, status: status})
+}
+
+// ErrorStatus returns the HTTP status mapped to err, or 500 when none match.
+func (a *App) ErrorStatus(err error) int {
+	if a != nil {
+		for i := len(a.errorStatuses) - 1; i >= 0; i-- {
+			if errors.Is(err, a.errorStatuses[i].err) {
+				return a.errorStatuses[i].status
+			}
+		}
+	}
+	for _, m := range builtinErrorStatuses {
+		if errors.Is(err, m.err) {
+			return m.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Fail responds to err with its mapped status (see App.RegisterErrorStatus,
+// default 500). JSON clients get the JSONError problem envelope; others get
+// the "errors/<status>" view (eg. views/errors/404.html, rendered with
+// Status and Message) when one exists, or a plain-text message. Server
+// errors are logged and their message is replaced by the status text so
+// internals don't leak.
+func (c *Context) Fail(err error) {
+	if err == nil {
+		return
+	}
+	status := c.App.ErrorStatus(err)
+	msg := err.Error()
+	if status >= http.StatusInternalServerError {
+		c.Logger().Printf("%s %s: %v", c.R.Method, c.R.URL.Path, err)
+		msg = http.StatusText(status)
+	}
+	if c.WantsJSON() {
+		_ = c.JSONError(status, msg)
+		return
+	}
+	if c.App != nil && c.App.Views != nil {
+		data := map[string]interface{}{"Status": status, "Message": msg}
+		if c.RenderError(status, "errors/"+strconv.Itoa(status), data) == nil {
+			return
+		}
+	}
+	c.Error(status, msg)
+}
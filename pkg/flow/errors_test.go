@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContext_FailMapsStatus(t *testing.T) {
+	app := New("testapp")
+	app.Views.TemplateDir = t.TempDir()
+	errBanned := errors.New("banned")
+	app.RegisterErrorStatus(errBanned, http.StatusForbidden)
+
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{ErrNotFound, http.StatusNotFound},
+		{fmt.Errorf("post 7: %w", ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: title is required", ErrValidation), http.StatusUnprocessableEntity},
+		{errBanned, http.StatusForbidden},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		NewContext(app, rr, httptest.NewRequest("GET", "/", nil)).Fail(tc.err)
+		if rr.Code != tc.status {
+			t.Errorf("Fail(%v): expected %d, got %d", tc.err, tc.status, rr.Code)
+		}
+	}
+}
+
+func TestContext_FailJSONAndErrorPage(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "errors", "404.html"), "{{define \"content\"}}<h1>{{.Status}}</h1>{{end}}")
+	app := New("testapp")
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	NewContext(app, rr, httptest.NewRequest("GET", "/posts/9", nil)).Fail(ErrNotFound)
+	if rr.Code != http.StatusNotFound || rr.Body.String() != "<h1>404</h1>" {
+		t.Fatalf("expected 404 error page, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/posts/9", nil)
+	req.Header.Set("Accept", "application/json")
+	NewContext(app, rr, req).Fail(errors.New("db password leaked"))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, `"status":500`) || strings.Contains(body, "password") {
+		t.Fatalf("unexpected problem body %q", body)
+	}
+}
@@ -0,0 +1,74 @@
+package flowtest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	flow "github.com/dministrator/flow/pkg/flow"
+)
+
+// itemsController is a tiny controller used to demonstrate action tests.
+type itemsController struct{ *flow.Controller }
+
+func (c *itemsController) Show(ctx *flow.Context) {
+	_ = ctx.JSON(http.StatusOK, map[string]string{"id": ctx.Param("id")})
+}
+
+func (c *itemsController) Create(ctx *flow.Context) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := ctx.BindJSON(&in); err != nil {
+		ctx.Error(http.StatusBadRequest, err.Error())
+		return
+	}
+	_ = ctx.JSON(http.StatusCreated, map[string]string{"name": in.Name})
+}
+
+func TestNewTestContext_ShowJSON(t *testing.T) {
+	app := flow.New("flowtest")
+	c := &itemsController{Controller: flow.NewController(app)}
+
+	ctx, rec := NewTestContext(app, "GET", "/items/42", nil)
+	SetParams(ctx, map[string]string{"id": "42"})
+	c.Show(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var got map[string]string
+	if err := DecodeJSON(rec, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != "42" {
+		t.Fatalf("expected id 42, got %v", got)
+	}
+}
+
+func TestNewTestContext_CreateWithBody(t *testing.T) {
+	c := &itemsController{Controller: flow.NewController(nil)}
+
+	ctx, rec := NewTestContext(nil, "POST", "/items", strings.NewReader(`{"name":"widget"}`))
+	c.Create(ctx)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	var got map[string]string
+	if err := DecodeJSON(rec, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["name"] != "widget" {
+		t.Fatalf("expected name widget, got %v", got)
+	}
+}
+
+func TestSetParam_KeepsExisting(t *testing.T) {
+	ctx, _ := NewTestContext(nil, "GET", "/", nil)
+	SetParam(ctx, "post_id", "1")
+	SetParam(ctx, "id", "2")
+	if ctx.Param("post_id") != "1" || ctx.Param("id") != "2" {
+		t.Fatalf("unexpected params: %v", ctx.Params())
+	}
+}
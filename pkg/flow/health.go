@@ -0,0 +1,125 @@
+// Package flow: readiness checks.
+//
+// Readiness answers "can this instance take traffic?". Checks are registered
+// on the App with AddReadinessCheck and evaluated by ReadinessHandler, which
+// responds 200 when every check passes and 503 otherwise, with a JSON body
+// naming each check's result. Mount it wherever the platform probes:
+//
+//	app.AddReadinessCheck("db", flow.DBPing())
+//	app.AddReadinessCheck("migrations", flow.MigrationsUpToDate("db/migrate"))
+//	app.Mount("/readyz", app.ReadinessHandler())
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	migrations "github.com/dministrator/flow/internal/migrations"
+)
+
+// ReadinessCheck reports whether a dependency of app is ready. A non-nil
+// error marks the App as not ready.
+type ReadinessCheck func(ctx context.Context, app *App) error
+
+// readinessCheck is a ReadinessCheck registered under a name.
+type readinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// AddReadinessCheck registers check under name. Checks run in registration
+// order each time readiness is evaluated. Register checks during setup,
+// before the App starts serving.
+func (a *App) AddReadinessCheck(name string, check ReadinessCheck) {
+	if a == nil || check == nil {
+		return
+	}
+	a.readiness = append(a.readiness, readinessCheck{name: name, check: check})
+}
+
+// CheckReadiness runs every registered check and returns the failures keyed
+// by check name. An empty map means the App is ready.
+func (a *App) CheckReadiness(ctx context.Context) map[string]error {
+	failed := map[string]error{}
+	for _, rc := range a.readiness {
+		if err := rc.check(ctx, a); err != nil {
+			failed[rc.name] = err
+		}
+	}
+	return failed
+}
+
+// readinessReport is the JSON body written by ReadinessHandler.
+type readinessReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ReadinessHandler returns a handler that runs the readiness checks and
+// responds 200 {"status":"ready"} or 503 {"status":"not ready"}, listing
+// "ok" or the error for each check.
+func (a *App) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed := a.CheckReadiness(r.Context())
+		report := readinessReport{Status: "ready", Checks: map[string]string{}}
+		for _, rc := range a.readiness {
+			report.Checks[rc.name] = "ok"
+			if err, ok := failed[rc.name]; ok {
+				report.Checks[rc.name] = err.Error()
+			}
+		}
+		status := http.StatusOK
+		if len(failed) > 0 {
+			report.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", DefaultJSONContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// DBPing is a ReadinessCheck that pings the App's database.
+func DBPing() ReadinessCheck {
+	return func(ctx context.Context, app *App) error {
+		db := app.DB()
+		if db == nil {
+			return fmt.Errorf("database not configured")
+		}
+		return db.PingContext(ctx)
+	}
+}
+
+// MigrationsUpToDate is a ReadinessCheck that fails while any migration in
+// dir has not been applied to the App's database, catching deploys that
+// forgot to migrate before traffic arrives. Applied migrations are read
+// from the default tracking table; see MigrationsUpToDateIn.
+func MigrationsUpToDate(dir string) ReadinessCheck {
+	return MigrationsUpToDateIn(dir, "", "")
+}
+
+// MigrationsUpToDateIn is MigrationsUpToDate for apps that track migrations
+// in a custom table (and optional schema), as configured with the CLI's
+// --table and --schema flags. The check only reads: a missing tracking
+// table fails it rather than being created.
+func MigrationsUpToDateIn(dir, table, schema string) ReadinessCheck {
+	runner := &migrations.MigrationRunner{TableName: table, Schema: schema}
+	return func(ctx context.Context, app *App) error {
+		db := app.DB()
+		if db == nil {
+			return fmt.Errorf("database not configured")
+		}
+		pending, err := runner.UnappliedMigrations(dir, db)
+		if err != nil {
+			return fmt.Errorf("check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s): %s", len(pending), strings.Join(pending, ", "))
+		}
+		return nil
+	}
+}
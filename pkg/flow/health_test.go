@@ -0,0 +1,95 @@
+package flow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	migrations "github.com/dministrator/flow/internal/migrations"
+	orm "github.com/dministrator/flow/internal/orm"
+	_ "modernc.org/sqlite"
+)
+
+func TestReadinessMigrationsUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "20260101000000_create_notes.up.sql"), []byte("CREATE TABLE notes (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	adapter, err := orm.Connect("file:" + filepath.Join(dir, "ready.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer adapter.Close()
+
+	app := New("ready-test", WithBun(adapter))
+	app.AddReadinessCheck("db", DBPing())
+	app.AddReadinessCheck("migrations", MigrationsUpToDate(dir))
+	h := app.ReadinessHandler()
+
+	probe := func() (int, readinessReport) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+		var rep readinessReport
+		if err := json.NewDecoder(rr.Body).Decode(&rep); err != nil {
+			t.Fatalf("decode report: %v", err)
+		}
+		return rr.Code, rep
+	}
+
+	code, rep := probe()
+	if code != http.StatusServiceUnavailable || rep.Status != "not ready" {
+		t.Fatalf("expected not ready with a pending migration, got %d %+v", code, rep)
+	}
+	if rep.Checks["db"] != "ok" || rep.Checks["migrations"] == "ok" {
+		t.Fatalf("unexpected check results: %+v", rep.Checks)
+	}
+
+	if err := (&migrations.MigrationRunner{}).ApplyAll(dir, adapter.SQLDB); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	code, rep = probe()
+	if code != http.StatusOK || rep.Status != "ready" || rep.Checks["migrations"] != "ok" {
+		t.Fatalf("expected ready after migrating, got %d %+v", code, rep)
+	}
+}
+
+func TestReadinessMigrationsUpToDateIn(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "20260101000000_create_notes.up.sql"), []byte("CREATE TABLE notes (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	adapter, err := orm.Connect("file:" + filepath.Join(dir, "ready.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer adapter.Close()
+
+	app := New("ready-test", WithBun(adapter))
+	check := MigrationsUpToDateIn(dir, "app_migrations", "")
+	ctx := httptest.NewRequest("GET", "/readyz", nil).Context()
+
+	// the probe must not create the tracking table
+	if err := check(ctx, app); err == nil {
+		t.Fatalf("expected the check to fail before migrating")
+	}
+	var n int
+	if err := adapter.SQLDB.QueryRow("SELECT count(*) FROM sqlite_master WHERE name IN ('app_migrations', ?)", migrations.DefaultTableName).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("readiness check created a tracking table")
+	}
+
+	if err := (&migrations.MigrationRunner{TableName: "app_migrations"}).ApplyAll(dir, adapter.SQLDB); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	if err := check(ctx, app); err != nil {
+		t.Fatalf("expected ready with the custom tracking table, got %v", err)
+	}
+	if err := MigrationsUpToDate(dir)(ctx, app); err == nil {
+		t.Fatalf("expected the default table check to fail for an app using a custom table")
+	}
+}
@@ -0,0 +1,229 @@
+// Package flow: idempotency keys.
+//
+// Clients retrying a POST after a timeout cannot tell whether the first
+// attempt went through. With Idempotency they send an Idempotency-Key
+// header; the first response for a key is stored and replayed for retries,
+// so the action runs once:
+//
+//	r.PostWith("/payments", payments.Create, flow.Idempotency())
+package flow
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long Idempotency remembers a response.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotentResponse is a stored response replayed for repeated keys.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps responses by key for IdempotencyWithStore.
+// Implementations must be safe for concurrent use; a shared store (eg.
+// backed by Redis) makes keys work across several app instances.
+type IdempotencyStore interface {
+	// Begin claims key for ttl. It returns the stored response when key
+	// has completed, or inFlight when another request holds the claim.
+	// Otherwise the caller owns key and must Complete or Release it.
+	Begin(key string, ttl time.Duration) (resp *IdempotentResponse, inFlight bool, err error)
+	// Complete stores resp for key, to be replayed until ttl passes.
+	Complete(key string, resp IdempotentResponse, ttl time.Duration) error
+	// Release drops the claim on key so a later request can retry.
+	Release(key string) error
+}
+
+// Idempotency is IdempotencyWithStore with a new in-memory store and
+// DefaultIdempotencyTTL.
+func Idempotency() Middleware {
+	return IdempotencyWithStore(NewMemoryIdempotencyStore(), DefaultIdempotencyTTL)
+}
+
+// IdempotencyWithStore replays responses for unsafe requests (POST, PUT,
+// PATCH, DELETE) carrying an Idempotency-Key header. Keys are scoped by
+// method and path. The first request for a key runs the handler and its
+// response is stored for ttl; repeats get the stored status, headers and
+// body with Idempotent-Replayed: true, and a repeat arriving while the
+// first is still running gets 409. Responses with a 5xx status are not
+// stored, so the client can retry them. Requests without the header pass
+// through.
+func IdempotencyWithStore(store IdempotencyStore, ttl time.Duration) Middleware {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = r.Method + " " + r.URL.Path + " " + key
+			resp, inFlight, err := store.Begin(key, ttl)
+			switch {
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			case resp != nil:
+				for k, v := range resp.Header {
+					w.Header()[k] = slices.Clone(v)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(resp.Status)
+				_, _ = w.Write(resp.Body)
+				return
+			case inFlight:
+				http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+				return
+			}
+
+			rec := newIdempotencyRecorder(w)
+			completed := false
+			defer func() {
+				if !completed {
+					_ = store.Release(key)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+				rec.header = handlerHeaders(rec.before, w.Header())
+			}
+			if rec.status >= 500 {
+				return
+			}
+			completed = store.Complete(key, IdempotentResponse{
+				Status: rec.status,
+				Header: rec.header,
+				Body:   rec.body.Bytes(),
+			}, ttl) == nil
+		})
+	}
+}
+
+// isSafeMethod reports whether method is read-only by definition.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// idempotencyRecorder writes through to the client while keeping a copy of
+// the response for the store. Only the headers set by the handler are
+// kept: headers already present when it was created belong to outer
+// middleware for the current request (eg. X-Request-ID) and must not be
+// replayed onto later ones.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	before http.Header
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newIdempotencyRecorder(w http.ResponseWriter) *idempotencyRecorder {
+	return &idempotencyRecorder{ResponseWriter: w, before: w.Header().Clone()}
+}
+
+func (rw *idempotencyRecorder) WriteHeader(code int) {
+	if rw.status != 0 {
+		return
+	}
+	rw.status = code
+	rw.header = handlerHeaders(rw.before, rw.Header())
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// handlerHeaders returns the headers in after that were added or changed
+// since before.
+func handlerHeaders(before, after http.Header) http.Header {
+	out := http.Header{}
+	for k, vs := range after {
+		if prev, ok := before[k]; ok && slices.Equal(prev, vs) {
+			continue
+		}
+		out[k] = slices.Clone(vs)
+	}
+	return out
+}
+
+func (rw *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// memoryIdempotencyEntry is a claimed key; resp is nil while in flight.
+type memoryIdempotencyEntry struct {
+	resp    *IdempotentResponse
+	expires time.Time
+}
+
+// memoryIdempotencySweep is how often MemoryIdempotencyStore drops expired
+// keys.
+const memoryIdempotencySweep = time.Minute
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. Expired keys
+// are dropped lazily.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]memoryIdempotencyEntry{}}
+}
+
+// Begin implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > memoryIdempotencySweep {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if e, ok := s.entries[key]; ok && !now.After(e.expires) {
+		return e.resp, e.resp == nil, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{expires: now.Add(ttl)}
+	return nil, false, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Complete(key string, resp IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{resp: &resp, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package flow
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	var runs int32
+	h := Idempotency()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&runs, 1)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"order":%d}`, n)
+	}))
+	post := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"qty":1}`))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first, second := post("/orders", "k1"), post("/orders", "k1")
+	if runs != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", runs)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() || second.Header().Get("Location") != "/orders/1" {
+		t.Fatalf("expected the first response to be replayed, got %d %q %v", second.Code, second.Body.String(), second.Header())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected only the replay to be marked")
+	}
+
+	// the key is scoped by path, and requests without a key always run
+	post("/carts", "k1")
+	post("/orders", "")
+	post("/orders", "")
+	if runs != 4 {
+		t.Fatalf("expected 4 runs, got %d", runs)
+	}
+
+	// server errors are not stored, so a retry runs again
+	post("/fail", "k2")
+	post("/fail", "k2")
+	if runs != 6 {
+		t.Fatalf("expected failed requests to be retried, got %d runs", runs)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := IdempotencyWithStore(NewMemoryIdempotencyStore(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	}))
+	req := func() *http.Request {
+		r := httptest.NewRequest("POST", "/pay", nil)
+		r.Header.Set(IdempotencyKeyHeader, "k")
+		return r
+	}
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req())
+		done <- rec
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req())
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while the first request runs, got %d", rec.Code)
+	}
+	close(release)
+	if first := <-done; first.Code != http.StatusOK || first.Body.String() != "done" {
+		t.Fatalf("unexpected first response %d %q", first.Code, first.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req())
+	if rec.Body.String() != "done" {
+		t.Fatalf("expected the completed response to be replayed, got %q", rec.Body.String())
+	}
+}
+
+func TestMemoryIdempotencyStore_Expiry(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	if err := s.Complete("k", IdempotentResponse{Status: 201}, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if resp, inFlight, _ := s.Begin("k", time.Minute); resp != nil || inFlight {
+		t.Fatalf("expected an expired key to be claimable again")
+	}
+	if resp, inFlight, _ := s.Begin("k", time.Minute); resp != nil || !inFlight {
+		t.Fatalf("expected the new claim to be in flight")
+	}
+	s.Release("k")
+	if _, inFlight, _ := s.Begin("k", time.Minute); inFlight {
+		t.Fatalf("expected a released key to be claimable")
+	}
+}
+
+func TestIdempotency_ReplaysOnlyHandlerHeaders(t *testing.T) {
+	var n int32
+	requestID := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-ID", fmt.Sprintf("req-%d", atomic.AddInt32(&n, 1)))
+			next.ServeHTTP(w, r)
+		})
+	}
+	h := requestID(Idempotency()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+	})))
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	post()
+	replay := post()
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("Location") != "/orders/1" {
+		t.Fatalf("expected a replay with the handler's headers, got %v", replay.Header())
+	}
+	if got := replay.Header().Get("X-Request-ID"); got != "req-2" {
+		t.Fatalf("replay overwrote the current request's X-Request-ID: %q", got)
+	}
+}
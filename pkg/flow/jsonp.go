@@ -0,0 +1,53 @@
+// Package flow: JSONP responses.
+//
+// JSONP wraps a JSON document in a call to a client-chosen function so
+// legacy widgets can load it with a <script> tag:
+//
+//	ctx.JSONP(http.StatusOK, ctx.Query("callback"), data)
+//
+// The callback name comes from the request, so it is checked to be a plain
+// (optionally dotted) JavaScript identifier before anything is written.
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// ErrInvalidCallback is returned by JSONP for a callback name that is not a
+// safe JavaScript identifier. Fail responds 400.
+var ErrInvalidCallback = errors.New("flow: invalid JSONP callback")
+
+// jsonpCallbackRe matches identifiers such as "cb", "$jsonp_1" and
+// "widgets.render".
+var jsonpCallbackRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// maxJSONPCallback bounds the callback name length.
+const maxJSONPCallback = 128
+
+// JSONP writes v as `callback(<json>);` with Content-Type
+// application/javascript. The body starts with an empty comment, which
+// defeats content-sniffing attacks on the callback name. An unsafe callback
+// returns an error wrapping ErrInvalidCallback and writes nothing.
+func (c *Context) JSONP(status int, callback string, v interface{}) error {
+	if len(callback) > maxJSONPCallback || !jsonpCallbackRe.MatchString(callback) {
+		return fmt.Errorf("render jsonp: %q: %w", callback, ErrInvalidCallback)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("render jsonp: %w", err)
+	}
+	c.SetHeader("Content-Type", "application/javascript; charset=utf-8")
+	c.SetHeader("X-Content-Type-Options", "nosniff")
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+	if _, err := fmt.Fprintf(c.W, "/**/%s(%s);", callback, b); err != nil {
+		return fmt.Errorf("render jsonp: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_JSONP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := NewContext(nil, rec, httptest.NewRequest("GET", "/widget?callback=widgets.render", nil))
+	if err := ctx.JSONP(200, ctx.Query("callback"), map[string]string{"html": "</script>"}); err != nil {
+		t.Fatalf("jsonp: %v", err)
+	}
+	if want := `/**/widgets.render({"html":"\u003c/script\u003e"});`; rec.Body.String() != want {
+		t.Fatalf("unexpected body:\n got %s\nwant %s", rec.Body.String(), want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/javascript; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	for _, cb := range []string{"", "alert(1)//", "cb;alert(1)", "1cb", "a..b", "<script>", "cb\n"} {
+		rec := httptest.NewRecorder()
+		ctx := NewContext(nil, rec, httptest.NewRequest("GET", "/widget", nil))
+		if err := ctx.JSONP(200, cb, 1); !errors.Is(err, ErrInvalidCallback) {
+			t.Fatalf("%q: expected ErrInvalidCallback, got %v", cb, err)
+		}
+		if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+			t.Fatalf("%q: expected nothing written", cb)
+		}
+	}
+}
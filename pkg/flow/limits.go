@@ -0,0 +1,106 @@
+// Package flow: request size limits.
+//
+// MaxBodyBytes (or WithMaxBodyBytes and WithRequestLimits for the whole
+// App) rejects request bodies over a fixed size with 413, whether the
+// Content-Length announces it up front or the handler only finds out while
+// reading.
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// MaxBodyBytes caps request bodies at n bytes. A request whose
+// Content-Length exceeds n gets 413 without reaching the handler. Other
+// bodies are wrapped so reading past n fails with an error matching
+// ErrBodyTooLarge (Fail responds 413), and the response status becomes 413
+// even when the handler ignores that error, as long as it has not written
+// its headers yet. n <= 0 disables the limit.
+func MaxBodyBytes(n int64) Middleware {
+	if n <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				w.Header().Set("Connection", "close")
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			lw := &bodyLimitWriter{ResponseWriter: w}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, n), exceeded: &lw.exceeded}
+			}
+			next.ServeHTTP(lw, r)
+			if lw.exceeded.Load() && !lw.wroteHeader {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+// limitedBody reports reads past the limit as ErrBodyTooLarge (still
+// matching *http.MaxBytesError) and records that the limit was hit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded *atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		b.exceeded.Store(true)
+	}
+	return n, bodyReadErr(err)
+}
+
+// bodyReadErr makes an error from reading past an http.MaxBytesReader
+// limit match ErrBodyTooLarge, whoever installed the reader.
+func bodyReadErr(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) && !errors.Is(err, ErrBodyTooLarge) {
+		return fmt.Errorf("%w: %w", ErrBodyTooLarge, err)
+	}
+	return err
+}
+
+// bodyLimitWriter turns the handler's status into 413 once the body limit
+// was exceeded.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	exceeded    atomic.Bool
+	wroteHeader bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.exceeded.Load() {
+		code = http.StatusRequestEntityTooLarge
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush forwards to the underlying writer when it supports http.Flusher.
+func (w *bodyLimitWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,130 @@
+package flow
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestLimits_Body(t *testing.T) {
+	app := New("limits-test", WithRequestLimits(16, 0))
+	r := NewRouter(app)
+	var bindErr error
+	r.Post("/bind", func(c *Context) {
+		var v map[string]string
+		if bindErr = c.BindJSON(&v); bindErr != nil {
+			c.Fail(bindErr)
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+	// reads lazily and ignores the error
+	r.Post("/lazy", func(c *Context) {
+		io.Copy(io.Discard, c.R.Body)
+		c.JSON(http.StatusCreated, map[string]bool{"ok": true})
+	})
+	app.SetRouter(r)
+	h := app.Handler()
+
+	big := `{"name":"` + strings.Repeat("x", 64) + `"}`
+	cases := []struct {
+		path, body string
+		chunked    bool
+		want       int
+	}{
+		{"/bind", `{"a":"b"}`, false, http.StatusOK},
+		{"/bind", big, false, http.StatusRequestEntityTooLarge},
+		{"/bind", big, true, http.StatusRequestEntityTooLarge},
+		{"/lazy", big, true, http.StatusRequestEntityTooLarge},
+		{"/lazy", `{}`, true, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		if tc.chunked {
+			// no Content-Length: the limit is only hit while reading
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s (chunked %v, %d bytes): expected %d, got %d", tc.path, tc.chunked, len(tc.body), tc.want, rec.Code)
+		}
+	}
+	if !errors.Is(bindErr, ErrBodyTooLarge) {
+		t.Fatalf("expected the bind error to match ErrBodyTooLarge, got %v", bindErr)
+	}
+}
+
+func TestWithRequestLimits_Headers(t *testing.T) {
+	app := New("limits-test", WithRequestLimits(0, 1<<10))
+	app.SetRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = app.newServer()
+	ts.Start()
+	defer ts.Close()
+
+	get := func(headerSize int) int {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header.Set("X-Padding", strings.Repeat("a", headerSize))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if code := get(100); code != http.StatusNoContent {
+		t.Fatalf("expected small headers to pass, got %d", code)
+	}
+	// net/http allows 4KiB of slack on top of MaxHeaderBytes
+	if code := get(16 << 10); code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected 431 for large headers, got %d", code)
+	}
+}
+
+func TestWithMaxBodyBytes(t *testing.T) {
+	app := New("limits-test", WithMaxBodyBytes(32))
+	r := NewRouter(app)
+	r.Post("/items", func(c *Context) {
+		var v map[string]interface{}
+		if err := c.BindJSON(&v); err != nil {
+			c.Fail(err)
+			return
+		}
+		c.JSON(http.StatusCreated, v)
+	})
+	app.SetRouter(r)
+
+	for body, want := range map[string]int{
+		`{"name":"ok"}`: http.StatusCreated,
+		`{"name":"` + strings.Repeat("x", 64) + `"}`: http.StatusRequestEntityTooLarge,
+	} {
+		rec := httptest.NewRecorder()
+		app.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/items", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Fatalf("%d byte body: expected %d, got %d", len(body), want, rec.Code)
+		}
+	}
+}
+
+func TestBindJSON_MaxBytesReader(t *testing.T) {
+	// a limit installed outside flow is reported the same way
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/items", strings.NewReader(`{"name":"`+strings.Repeat("x", 64)+`"}`))
+	req.Body = http.MaxBytesReader(rec, req.Body, 16)
+	ctx := NewContext(nil, rec, req)
+	var v map[string]string
+	err := ctx.BindJSON(&v)
+	if !errors.Is(err, ErrBodyTooLarge) || !strings.Contains(err.Error(), "request body too large") {
+		t.Fatalf("expected a body too large error, got %v", err)
+	}
+	ctx.Fail(err)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 from Fail, got %d", rec.Code)
+	}
+}
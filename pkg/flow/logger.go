@@ -0,0 +1,156 @@
+// Package flow: logging helpers.
+//
+// Logger is deliberately tiny (Printf only). This file provides a no-op
+// logger, an in-memory logger for tests, and per-request loggers that carry
+// request fields (request ID, method, path) so handler log lines can be
+// correlated without threading values around manually.
+// Loggers that support structured fields can implement FieldLogger; plain
+// Printf loggers get the fields as a "key=value" prefix.
+package flow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// QuietEnv is the environment variable that, when set to a non-empty value
+// other than "0" or "false", makes New default to NopLogger instead of
+// logging to stdout. An explicit WithLogger option still takes precedence.
+const QuietEnv = "FLOW_QUIET"
+
+// nopLogger discards everything.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// NopLogger returns a Logger that discards all output.
+func NopLogger() Logger { return nopLogger{} }
+
+// TestLogger is a Logger that records formatted lines in memory so tests
+// can assert on log output. It is safe for concurrent use.
+type TestLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// NewTestLogger returns a TestLogger and a function returning a snapshot of
+// the lines logged so far.
+func NewTestLogger() (*TestLogger, func() []string) {
+	l := &TestLogger{}
+	return l, l.Lines
+}
+
+// Printf records the formatted message as a single line.
+func (l *TestLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+// Lines returns a copy of the recorded lines.
+func (l *TestLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+// quietRequested reports whether QuietEnv asks for silent logging.
+func quietRequested() bool {
+	v := strings.TrimSpace(strings.ToLower(os.Getenv(QuietEnv)))
+	return v != "" && v != "0" && v != "false"
+}
+
+// FieldLogger is an optional extension of Logger for structured loggers.
+// When the App logger implements it, request loggers are derived with
+// WithFields instead of prefixing each message (eg. an adapter around
+// slog.Logger.With).
+type FieldLogger interface {
+	Logger
+	WithFields(fields map[string]string) Logger
+}
+
+// prefixLogger decorates a Logger by prefixing every message with fields.
+type prefixLogger struct {
+	base   Logger
+	prefix string
+}
+
+func (l *prefixLogger) Printf(format string, v ...interface{}) {
+	l.base.Printf("%s%s", l.prefix, fmt.Sprintf(format, v...))
+}
+
+// WithFields returns a Logger that includes fields on every message. Keys
+// are rendered in sorted order so output is stable.
+func WithFields(l Logger, fields map[string]string) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.WithFields(fields)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s ", k, fields[k])
+	}
+	return &prefixLogger{base: l, prefix: b.String()}
+}
+
+// loggerCtxKey is the context key for the per-request logger.
+type loggerCtxKey struct{}
+
+// requestIDCtxKey is the context key for the request ID set by
+// RequestIDMiddleware.
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the request ID stored by
+// RequestIDMiddleware, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// requestFields returns the standard per-request logging fields.
+func requestFields(r *http.Request) map[string]string {
+	fields := map[string]string{"method": r.Method, "path": r.URL.Path}
+	id := RequestIDFromContext(r.Context())
+	if id == "" {
+		id = r.Header.Get("X-Request-ID")
+	}
+	if id != "" {
+		fields["request_id"] = id
+	}
+	return fields
+}
+
+// withRequestLogger stores a request-scoped logger derived from l on r.
+func withRequestLogger(r *http.Request, l Logger) *http.Request {
+	rl := WithFields(l, requestFields(r))
+	return r.WithContext(context.WithValue(r.Context(), loggerCtxKey{}, rl))
+}
+
+// LoggerFromContext returns the request-scoped logger stored by
+// LoggingMiddleware, or nil when none is present.
+func LoggerFromContext(ctx context.Context) Logger {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value(loggerCtxKey{}).(Logger)
+	return l
+}
@@ -0,0 +1,32 @@
+package flow
+
+import (
+	"testing"
+)
+
+func TestTestLogger_CapturesLines(t *testing.T) {
+	logger, lines := NewTestLogger()
+	app := New("logger-test", WithLogger(logger))
+
+	app.logger.Printf("hello %s", "world")
+	WithFields(logger, map[string]string{"b": "2", "a": "1"}).Printf("with fields")
+
+	got := lines()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(got), got)
+	}
+	if got[0] != "hello world" {
+		t.Fatalf("unexpected first line: %q", got[0])
+	}
+	if got[1] != "a=1 b=2 with fields" {
+		t.Fatalf("unexpected field line: %q", got[1])
+	}
+}
+
+func TestNew_QuietEnvUsesNopLogger(t *testing.T) {
+	t.Setenv(QuietEnv, "1")
+	app := New("quiet")
+	if _, ok := app.logger.(nopLogger); !ok {
+		t.Fatalf("expected nop logger when %s is set, got %T", QuietEnv, app.logger)
+	}
+}
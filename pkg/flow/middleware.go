@@ -2,18 +2,26 @@ package flow
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+
+	routerpkg "github.com/dministrator/flow/internal/router"
 )
 
-// LoggingMiddleware logs basic request info using the provided Logger.
+// LoggingMiddleware logs basic request info using the provided Logger. It
+// also stores a request-scoped logger (carrying request ID, method and path)
+// that handlers retrieve via Context.Logger.
 func LoggingMiddleware(logger Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			r = withRequestLogger(r, logger)
 			logger.Printf("request start: %s %s", r.Method, r.URL.Path)
 			next.ServeHTTP(w, r)
 			logger.Printf("request complete: %s %s in %s", r.Method, r.URL.Path, time.Since(start))
@@ -21,7 +29,8 @@ func LoggingMiddleware(logger Logger) Middleware {
 	}
 }
 
-// RequestIDMiddleware sets a request id header for tracing.
+// RequestIDMiddleware sets a request id header for tracing. The ID is also
+// stored on the request context (see RequestIDFromContext).
 func RequestIDMiddleware(headerName string) Middleware {
 	if headerName == "" {
 		headerName = "X-Request-ID"
@@ -34,6 +43,7 @@ func RequestIDMiddleware(headerName string) Middleware {
 				r.Header.Set(headerName, id)
 			}
 			w.Header().Set(headerName, id)
+			r = r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id))
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -58,6 +68,76 @@ func TimeoutMiddleware(d time.Duration) Middleware {
 	}
 }
 
+// Concurrency limits the wrapped handler to max requests at a time, eg. to
+// keep a heavy report from exhausting the database pool. Requests beyond
+// the limit get 503 with Retry-After rather than piling up. Each call
+// creates its own limit, so use it per route (HandleWith/GetWith) or
+// globally via WithConcurrencyLimit. max <= 0 disables the limit.
+func Concurrency(max int) Middleware {
+	return ConcurrencyWait(max, 0)
+}
+
+// ConcurrencyWait is like Concurrency but queues a request for up to wait
+// for a free slot before responding 503. A request whose context ends while
+// queued is dropped without running the handler.
+func ConcurrencyWait(max int, wait time.Duration) Middleware {
+	if max <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	sem := make(chan struct{}, max)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+			default:
+				if !acquireWithin(r.Context(), sem, wait) {
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+			}
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquireWithin waits up to wait for a slot in sem.
+func acquireWithin(ctx context.Context, sem chan struct{}, wait time.Duration) bool {
+	if wait <= 0 {
+		return false
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SkipIf wraps mw so requests matching pred bypass it and go straight to
+// the next handler, eg. to exempt /login and assets from an auth check:
+//
+//	app.Use(flow.SkipIf(requireLogin, func(r *http.Request) bool {
+//		return r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/static/")
+//	}))
+func SkipIf(mw Middleware, pred func(*http.Request) bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
 // MetricsMiddleware records simple timing metrics and sets an X-Response-Time header.
 func MetricsMiddleware() Middleware {
 	return func(next http.Handler) http.Handler {
@@ -69,3 +149,116 @@ func MetricsMiddleware() Middleware {
 		})
 	}
 }
+
+// startTimeCtxKey is the context key for the request start time.
+type startTimeCtxKey struct{}
+
+// StartTimeFromContext returns the time the App began handling the request
+// and whether it was recorded.
+func StartTimeFromContext(ctx context.Context) (time.Time, bool) {
+	if ctx == nil {
+		return time.Time{}, false
+	}
+	t, ok := ctx.Value(startTimeCtxKey{}).(time.Time)
+	return t, ok
+}
+
+// withStartTime records the request start time on the request context. The
+// App installs it outside all user middleware so timings cover the full
+// handling of the request.
+func withStartTime(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := StartTimeFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), startTimeCtxKey{}, time.Now())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// DefaultTraceHeaders are the headers captured by TraceMiddleware when none
+// are configured.
+var DefaultTraceHeaders = []string{"traceparent", "tracestate", "X-Request-ID"}
+
+// traceCtxKey is the context key for captured trace headers.
+type traceCtxKey struct{}
+
+// TraceMiddleware captures correlation headers from incoming requests so
+// handlers can propagate them on outbound calls (see Context.TraceHeaders).
+// When headers is empty DefaultTraceHeaders is used. If the request carries
+// no traceparent a new W3C traceparent is generated, stored with the
+// captured headers and set on the request.
+func TraceMiddleware(headers ...string) Middleware {
+	if len(headers) == 0 {
+		headers = DefaultTraceHeaders
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured := http.Header{}
+			for _, h := range headers {
+				for _, v := range r.Header.Values(h) {
+					captured.Add(h, v)
+				}
+			}
+			if captured.Get("traceparent") == "" {
+				if tp, err := newTraceparent(); err == nil {
+					captured.Set("traceparent", tp)
+					r.Header.Set("traceparent", tp)
+				}
+			}
+			ctx := context.WithValue(r.Context(), traceCtxKey{}, captured)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TraceHeadersFromContext returns a copy of the headers captured by
+// TraceMiddleware, or an empty header when none were captured.
+func TraceHeadersFromContext(ctx context.Context) http.Header {
+	if ctx == nil {
+		return http.Header{}
+	}
+	if h, ok := ctx.Value(traceCtxKey{}).(http.Header); ok {
+		return h.Clone()
+	}
+	return http.Header{}
+}
+
+// newTraceparent generates a W3C trace context header with a random trace
+// and parent ID and the sampled flag set.
+func newTraceparent() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(b[:16]), hex.EncodeToString(b[16:])), nil
+}
+
+// PathNormalizer rewrites the request path before routing: duplicate
+// slashes are collapsed and "." segments dropped ("/users//./7/" becomes
+// "/users/7"), and with lowercase set the path is lowercased for legacy
+// case-insensitive URLs. Paths with a ".." segment are rejected with 400
+// instead of being resolved, so a request can never climb out of a prefix
+// that middleware or mounts checked before the rewrite.
+func PathNormalizer(lowercase bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clean, ok := routerpkg.CleanPath(r.URL.Path)
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			if lowercase {
+				clean = strings.ToLower(clean)
+			}
+			if clean != r.URL.Path {
+				r2 := r.Clone(r.Context())
+				r2.URL.Path = clean
+				r2.URL.RawPath = ""
+				r = r2
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package flow
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -60,3 +61,170 @@ func TestTimeoutMiddleware_CancelsHandler(t *testing.T) {
 		t.Fatalf("expected handler to observe cancellation and return 499, got %d", rr.Code)
 	}
 }
+
+func TestContextLogger_IncludesRequestID(t *testing.T) {
+	logger, lines := NewTestLogger()
+	app := New("test-logger", WithLogger(logger), WithRequestID(""), WithLogging())
+
+	r := NewRouter(app)
+	r.Get("/things", func(ctx *Context) {
+		ctx.Logger().Printf("handling things")
+		ctx.W.WriteHeader(http.StatusNoContent)
+	})
+	app.SetRouter(r)
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/things", nil))
+
+	id := rr.Header().Get("X-Request-ID")
+	if id == "" {
+		t.Fatalf("expected X-Request-ID response header")
+	}
+	want := "request_id=" + id
+	found := false
+	for _, line := range lines() {
+		if strings.Contains(line, "handling things") {
+			found = true
+			if !strings.Contains(line, want) || !strings.Contains(line, "method=GET") || !strings.Contains(line, "path=/things") {
+				t.Fatalf("expected request fields in log line, got %q", line)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("handler log line not found in: %q", lines())
+	}
+}
+
+func TestTraceMiddleware_CapturesAndGenerates(t *testing.T) {
+	app := New("trace-test")
+	app.Use(TraceMiddleware())
+	var got http.Header
+	app.SetRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = NewContext(app, w, r).TraceHeaders()
+	}))
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", incoming)
+	req.Header.Set("X-Request-ID", "abc")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Get("traceparent") != incoming || got.Get("X-Request-ID") != "abc" {
+		t.Fatalf("expected incoming trace headers to be captured, got %v", got)
+	}
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	tp := got.Get("traceparent")
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || parts[3] != "01" {
+		t.Fatalf("expected generated W3C traceparent, got %q", tp)
+	}
+}
+
+func TestPathNormalizer(t *testing.T) {
+	app := New("test-app", WithPathNormalizer(true))
+	r := NewRouter(app)
+	r.Get("/users/:id", func(ctx *Context) { _, _ = ctx.W.Write([]byte(ctx.Param("id"))) })
+	app.SetRouter(r)
+	h := app.Handler()
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/Users//./7/", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "7" {
+		t.Fatalf("expected normalized route to match, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.URL.Path = "/static/../users/7"
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected traversal to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestConcurrency_RejectsBeyondLimit(t *testing.T) {
+	const max = 2
+	started := make(chan struct{}, max)
+	release := make(chan struct{})
+	h := Concurrency(max)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	codes := make(chan int, max)
+	for i := 0; i < max; i++ {
+		go func() {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", "/report", nil))
+			codes <- rr.Code
+		}()
+	}
+	for i := 0; i < max; i++ {
+		<-started
+	}
+
+	// both slots are busy: the next request is turned away
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/report", nil))
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d %v", rr.Code, rr.Header())
+	}
+
+	close(release)
+	for i := 0; i < max; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("expected running requests to finish with 200, got %d", code)
+		}
+	}
+
+	// slots are freed once the handlers return
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/report", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 after slots freed, got %d", rr.Code)
+	}
+}
+
+func TestConcurrencyWait_QueuesUntilSlotFrees(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := ConcurrencyWait(1, time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	<-started
+	time.AfterFunc(20*time.Millisecond, func() { close(release) })
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/fast", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected queued request to run once a slot frees, got %d", rr.Code)
+	}
+}
+
+func TestSkipIf(t *testing.T) {
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	app := New("skipif-test", WithMiddleware(SkipIf(auth, func(r *http.Request) bool {
+		return r.URL.Path == "/login"
+	})))
+	app.SetRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for path, want := range map[string]int{"/dashboard": http.StatusUnauthorized, "/login": http.StatusOK} {
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+}
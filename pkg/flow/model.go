@@ -8,14 +8,17 @@ package flow
 
 import (
 	"database/sql"
+	"encoding/json"
+	"net/http"
 	"time"
 )
 
 // Model is a small embedding struct that generated models can include to
 // obtain standard fields. It deliberately uses primitive types to avoid
-// forcing an ORM implementation.
+// forcing an ORM implementation. ID is tagged as bun's autoincrement primary
+// key so Insert fills it in and Update/Delete can target the row by it.
 type Model struct {
-	ID        int64        `db:"id" json:"id"`
+	ID        int64        `db:"id" bun:"id,pk,autoincrement" json:"id"`
 	CreatedAt time.Time    `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time    `db:"updated_at" json:"updated_at"`
 	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at,omitempty"`
@@ -34,3 +37,51 @@ func (a *App) SetDB(db *sql.DB) {
 
 // DB returns the attached *sql.DB or nil if none was set.
 func (a *App) DB() *sql.DB { return a.db }
+
+// DBStats returns the connection pool statistics of the attached *sql.DB,
+// or zero stats when no database is attached.
+func (a *App) DBStats() sql.DBStats {
+	if a == nil || a.db == nil {
+		return sql.DBStats{}
+	}
+	return a.db.Stats()
+}
+
+// dbStatsReport is the JSON shape served by WithDBStats.
+type dbStatsReport struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// DBStatsHandler serves the App's pool statistics as JSON, or 503 when no
+// database is attached.
+func (a *App) DBStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", DefaultJSONContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		if a.DB() == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "database not configured"})
+			return
+		}
+		st := a.DBStats()
+		_ = json.NewEncoder(w).Encode(dbStatsReport{
+			MaxOpenConnections: st.MaxOpenConnections,
+			OpenConnections:    st.OpenConnections,
+			InUse:              st.InUse,
+			Idle:               st.Idle,
+			WaitCount:          st.WaitCount,
+			WaitDurationMS:     st.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      st.MaxIdleClosed,
+			MaxIdleTimeClosed:  st.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  st.MaxLifetimeClosed,
+		})
+	})
+}
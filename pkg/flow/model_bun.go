@@ -3,12 +3,19 @@
 // This file provides small helpers to work with bun from application code
 // via the App's Bun() accessor. It is intentionally minimal — a starting
 // point for generator integrations and migrations.
+//
+// The helpers use the default connection; UseConnection points them at a
+// named one (see App.SetNamedBun):
+//
+//	err := flow.FindByPK(flow.UseConnection(ctx, "analytics"), app, &report, id)
 package flow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/uptrace/bun"
 )
@@ -20,7 +27,7 @@ func AutoMigrate(ctx context.Context, app *App, models ...interface{}) error {
 	if app == nil {
 		return fmt.Errorf("app is nil")
 	}
-	db := app.Bun()
+	db := dbFor(ctx, app)
 	if db == nil {
 		return fmt.Errorf("bun DB not configured on app")
 	}
@@ -42,9 +49,31 @@ func DB(app *App) *bun.DB {
 	return app.Bun()
 }
 
+// connectionCtxKey carries the connection name set by UseConnection.
+type connectionCtxKey struct{}
+
+// UseConnection returns a copy of ctx that makes the helpers in this file
+// (Insert, FindByPK, RunInTx, ...) use the App's named connection instead
+// of the default one. An empty name selects the default.
+func UseConnection(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, connectionCtxKey{}, name)
+}
+
+// dbFor returns the connection selected for ctx, or nil when it is not
+// configured.
+func dbFor(ctx context.Context, app *App) *bun.DB {
+	if app == nil {
+		return nil
+	}
+	if name, _ := ctx.Value(connectionCtxKey{}).(string); name != "" {
+		return app.NamedBun(name)
+	}
+	return app.Bun()
+}
+
 // BeginTx starts a new transaction using the App's Bun DB.
 func BeginTx(ctx context.Context, app *App) (*bun.Tx, error) {
-	db := DB(app)
+	db := dbFor(ctx, app)
 	if db == nil {
 		return nil, fmt.Errorf("bun DB not configured on app")
 	}
@@ -83,7 +112,7 @@ func RunInTx(ctx context.Context, app *App, fn func(ctx context.Context, tx *bun
 
 // Insert inserts the provided model using bun.
 func Insert(ctx context.Context, app *App, model interface{}) error {
-	db := DB(app)
+	db := dbFor(ctx, app)
 	if db == nil {
 		return fmt.Errorf("bun DB not configured on app")
 	}
@@ -95,7 +124,7 @@ func Insert(ctx context.Context, app *App, model interface{}) error {
 
 // Update updates the provided model using its primary key.
 func Update(ctx context.Context, app *App, model interface{}) error {
-	db := DB(app)
+	db := dbFor(ctx, app)
 	if db == nil {
 		return fmt.Errorf("bun DB not configured on app")
 	}
@@ -115,9 +144,83 @@ func Update(ctx context.Context, app *App, model interface{}) error {
 	return nil
 }
 
+// UpdateColumns updates only the named columns of the model's row, leaving
+// all others untouched. It is the partial-update counterpart of Update,
+// intended for PATCH requests (see Context.BindPatch). With no columns it is
+// a no-op.
+func UpdateColumns(ctx context.Context, app *App, model interface{}, columns ...string) error {
+	db := dbFor(ctx, app)
+	if db == nil {
+		return fmt.Errorf("bun DB not configured on app")
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	rid, err := extractID(model)
+	if err != nil {
+		return err
+	}
+	if _, err := db.NewUpdate().Model(model).Column(columns...).Where("id = ?", rid).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ErrStaleObject is returned by UpdateIfUnchanged when the row was modified
+// (or deleted) since the model was loaded.
+var ErrStaleObject = errors.New("flow: stale object: row changed since it was loaded")
+
+// UpdateIfUnchanged updates the model only if its row still carries the
+// model's last-known `updated_at`, guarding against lost updates. The model
+// must have an ID and an UpdatedAt time.Time field (embedding flow.Model
+// provides both). On success UpdatedAt is advanced to the current time in UTC,
+// truncated to microseconds so it equals what Postgres (and MySQL
+// DATETIME(6)) store and the next guarded update from the same model still
+// matches; if no row matched, UpdatedAt is restored and ErrStaleObject is
+// returned.
+func UpdateIfUnchanged(ctx context.Context, app *App, model interface{}) error {
+	db := dbFor(ctx, app)
+	if db == nil {
+		return fmt.Errorf("bun DB not configured on app")
+	}
+	rid, err := extractID(model)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct")
+	}
+	f := v.Elem().FieldByName("UpdatedAt")
+	if !f.IsValid() || !f.CanSet() || f.Type() != reflect.TypeOf(time.Time{}) {
+		return fmt.Errorf("model does not have an UpdatedAt time.Time field")
+	}
+	prev := f.Interface().(time.Time)
+	f.Set(reflect.ValueOf(time.Now().UTC().Truncate(time.Microsecond)))
+
+	res, err := db.NewUpdate().Model(model).
+		Where("id = ?", rid).
+		Where("updated_at = ?", prev).
+		Exec(ctx)
+	if err != nil {
+		f.Set(reflect.ValueOf(prev))
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		f.Set(reflect.ValueOf(prev))
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		f.Set(reflect.ValueOf(prev))
+		return ErrStaleObject
+	}
+	return nil
+}
+
 // Delete removes the provided model using its primary key.
 func Delete(ctx context.Context, app *App, model interface{}) error {
-	db := DB(app)
+	db := dbFor(ctx, app)
 	if db == nil {
 		return fmt.Errorf("bun DB not configured on app")
 	}
@@ -157,7 +260,7 @@ func extractID(model interface{}) (interface{}, error) {
 
 // FindByPK loads a model by primary key into dest.
 func FindByPK(ctx context.Context, app *App, dest interface{}, pk interface{}) error {
-	db := DB(app)
+	db := dbFor(ctx, app)
 	if db == nil {
 		return fmt.Errorf("bun DB not configured on app")
 	}
@@ -166,3 +269,50 @@ func FindByPK(ctx context.Context, app *App, dest interface{}, pk interface{}) e
 	}
 	return nil
 }
+
+// Page size defaults used by Paginate and Context.PageParams.
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+// Pagination describes the page of results loaded by Paginate.
+type Pagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+// clampPage normalizes a 1-based page number and page size.
+func clampPage(page, perPage int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	return page, perPage
+}
+
+// Paginate loads one page of rows, ordered by id, into dest (a pointer to a
+// slice of models) and reports the total number of rows. page is 1-based;
+// perPage defaults to DefaultPerPage and is capped at MaxPerPage.
+func Paginate(ctx context.Context, app *App, dest interface{}, page, perPage int) (Pagination, error) {
+	db := dbFor(ctx, app)
+	if db == nil {
+		return Pagination{}, fmt.Errorf("bun DB not configured on app")
+	}
+	page, perPage = clampPage(page, perPage)
+	total, err := db.NewSelect().Model(dest).
+		Order("id ASC").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		ScanAndCount(ctx)
+	if err != nil {
+		return Pagination{}, fmt.Errorf("paginate: %w", err)
+	}
+	return Pagination{Page: page, PerPage: perPage, Total: total}, nil
+}
@@ -2,7 +2,10 @@ package flow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -164,3 +167,256 @@ func TestCRUDOperations(t *testing.T) {
 		t.Fatalf("expected committed row, scan failed: %v", err)
 	}
 }
+
+func TestUpdateIfUnchangedDetectsStaleObject(t *testing.T) {
+	adapter, err := orm.Connect("file::memory:?cache=shared")
+	if err != nil {
+		t.Fatalf("connect bun: %v", err)
+	}
+	defer adapter.Close()
+
+	app := New("bun-test-stale", WithBun(adapter))
+
+	type ItemStale struct {
+		ID        int64     `bun:"id,pk,autoincrement"`
+		Name      string    `bun:"name"`
+		UpdatedAt time.Time `bun:"updated_at"`
+	}
+
+	ctx := context.Background()
+	if err := AutoMigrate(ctx, app, (*ItemStale)(nil)); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	it := &ItemStale{Name: "original", UpdatedAt: time.Now()}
+	if err := Insert(ctx, app, it); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// two clients load the same row
+	var a, b ItemStale
+	if err := FindByPK(ctx, app, &a, it.ID); err != nil {
+		t.Fatalf("find a: %v", err)
+	}
+	if err := FindByPK(ctx, app, &b, it.ID); err != nil {
+		t.Fatalf("find b: %v", err)
+	}
+
+	// first writer wins
+	a.Name = "from-a"
+	if err := UpdateIfUnchanged(ctx, app, &a); err != nil {
+		t.Fatalf("guarded update a: %v", err)
+	}
+
+	// second writer saw an older updated_at and must be rejected
+	bPrev := b.UpdatedAt
+	b.Name = "from-b"
+	if err := UpdateIfUnchanged(ctx, app, &b); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("expected ErrStaleObject, got %v", err)
+	}
+	if !b.UpdatedAt.Equal(bPrev) {
+		t.Fatalf("expected UpdatedAt to be restored after stale update")
+	}
+
+	var got ItemStale
+	if err := FindByPK(ctx, app, &got, it.ID); err != nil {
+		t.Fatalf("find after: %v", err)
+	}
+	if got.Name != "from-a" {
+		t.Fatalf("expected from-a to persist, got %s", got.Name)
+	}
+
+	// a writer holding the fresh version can update again
+	a.Name = "from-a-again"
+	if err := UpdateIfUnchanged(ctx, app, &a); err != nil {
+		t.Fatalf("second guarded update a: %v", err)
+	}
+}
+
+func TestUpdateIfUnchangedTwiceWithoutReload(t *testing.T) {
+	adapter, err := orm.Connect("file::memory:?cache=shared")
+	if err != nil {
+		t.Fatalf("connect bun: %v", err)
+	}
+	defer adapter.Close()
+
+	app := New("bun-test-guarded-twice", WithBun(adapter))
+
+	type ItemGuarded struct {
+		ID        int64     `bun:"id,pk,autoincrement"`
+		Name      string    `bun:"name"`
+		UpdatedAt time.Time `bun:"updated_at"`
+	}
+
+	ctx := context.Background()
+	if err := AutoMigrate(ctx, app, (*ItemGuarded)(nil)); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	it := &ItemGuarded{Name: "v0", UpdatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	if err := Insert(ctx, app, it); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		it.Name = fmt.Sprintf("v%d", i)
+		if err := UpdateIfUnchanged(ctx, app, it); err != nil {
+			t.Fatalf("guarded update %d: %v", i, err)
+		}
+		if it.UpdatedAt.Location() != time.UTC || it.UpdatedAt.Nanosecond()%1000 != 0 {
+			t.Fatalf("expected UpdatedAt in UTC with microsecond precision, got %v", it.UpdatedAt)
+		}
+		// store the timestamp the way a microsecond-precision column does;
+		// the next guarded update must still match it
+		if _, err := app.Bun().NewUpdate().Model((*ItemGuarded)(nil)).
+			Set("updated_at = ?", it.UpdatedAt.Truncate(time.Microsecond)).
+			Where("id = ?", it.ID).Exec(ctx); err != nil {
+			t.Fatalf("truncate stored updated_at: %v", err)
+		}
+	}
+}
+
+func TestPatchUpdatesOnlyPresentFields(t *testing.T) {
+	adapter, err := orm.Connect("file::memory:?cache=shared")
+	if err != nil {
+		t.Fatalf("connect bun: %v", err)
+	}
+	defer adapter.Close()
+
+	app := New("bun-test-patch", WithBun(adapter))
+
+	type ItemPatch struct {
+		ID    int64  `bun:"id,pk,autoincrement" json:"id"`
+		Name  string `bun:"name" json:"name"`
+		Color string `bun:"color" json:"colour"`
+	}
+
+	ctx := context.Background()
+	if err := AutoMigrate(ctx, app, (*ItemPatch)(nil)); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	it := &ItemPatch{Name: "widget", Color: "red"}
+	if err := Insert(ctx, app, it); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// a stale copy whose Name differs from the DB: a full update would
+	// clobber it, a PATCH of colour must not.
+	stale := &ItemPatch{ID: it.ID, Name: "stale-name"}
+
+	req := httptest.NewRequest("PATCH", "/items/1", strings.NewReader(`{"colour":"blue"}`))
+	c := NewContext(app, httptest.NewRecorder(), req)
+	if !c.IsPatch() {
+		t.Fatalf("expected IsPatch for PATCH request")
+	}
+	cols, err := c.BindPatch(stale)
+	if err != nil {
+		t.Fatalf("bind patch: %v", err)
+	}
+	if len(cols) != 1 || cols[0] != "color" {
+		t.Fatalf("expected [color] columns, got %v", cols)
+	}
+	if err := UpdateColumns(ctx, app, stale, cols...); err != nil {
+		t.Fatalf("update columns: %v", err)
+	}
+
+	var got ItemPatch
+	if err := FindByPK(ctx, app, &got, it.ID); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.Color != "blue" || got.Name != "widget" {
+		t.Fatalf("expected only color to change, got %+v", got)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	adapter, err := orm.Connect("file:paginate_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("connect bun: %v", err)
+	}
+	defer adapter.Close()
+	app := New("paginate-test", WithBun(adapter))
+
+	type PageItem struct {
+		ID   int64  `bun:"id,pk,autoincrement"`
+		Name string `bun:"name"`
+	}
+	ctx := context.Background()
+	if err := AutoMigrate(ctx, app, (*PageItem)(nil)); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := Insert(ctx, app, &PageItem{Name: fmt.Sprintf("item%d", i)}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	var items []PageItem
+	p, err := Paginate(ctx, app, &items, 2, 2)
+	if err != nil {
+		t.Fatalf("paginate: %v", err)
+	}
+	if p.Total != 5 || p.Page != 2 || p.PerPage != 2 {
+		t.Fatalf("unexpected pagination: %+v", p)
+	}
+	if len(items) != 2 || items[0].Name != "item3" || items[1].Name != "item4" {
+		t.Fatalf("unexpected page items: %+v", items)
+	}
+
+	req := httptest.NewRequest("GET", "/items?page=0&per_page=1000", nil)
+	page, perPage := NewContext(app, httptest.NewRecorder(), req).PageParams()
+	if page != 1 || perPage != MaxPerPage {
+		t.Fatalf("expected clamped page params, got %d/%d", page, perPage)
+	}
+}
+
+func TestNamedBunConnections(t *testing.T) {
+	primary, err := orm.Connect("file:named_primary?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("connect primary: %v", err)
+	}
+	defer primary.Close()
+	analytics, err := orm.Connect("file:named_analytics?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("connect analytics: %v", err)
+	}
+	defer analytics.Close()
+
+	app := New("bun-test-named", WithBun(primary), WithNamedBun("analytics", analytics))
+	if app.NamedBun("") != app.Bun() || app.NamedBun("analytics") != analytics.DB {
+		t.Fatalf("unexpected named connections")
+	}
+	if app.NamedBun("reporting") != nil {
+		t.Fatalf("expected nil for an unknown connection")
+	}
+
+	type Event struct {
+		ID   int64  `bun:"id,pk,autoincrement"`
+		Name string `bun:"name"`
+	}
+	ctx := context.Background()
+	actx := UseConnection(ctx, "analytics")
+	for _, c := range []context.Context{ctx, actx} {
+		if err := AutoMigrate(c, app, (*Event)(nil)); err != nil {
+			t.Fatalf("auto migrate: %v", err)
+		}
+	}
+	if err := Insert(ctx, app, &Event{Name: "primary"}); err != nil {
+		t.Fatalf("insert primary: %v", err)
+	}
+	if err := Insert(actx, app, &Event{Name: "analytics"}); err != nil {
+		t.Fatalf("insert analytics: %v", err)
+	}
+
+	for c, want := range map[context.Context]string{ctx: "primary", actx: "analytics"} {
+		var got Event
+		if err := FindByPK(c, app, &got, 1); err != nil || got.Name != want {
+			t.Fatalf("expected %q from its own database, got %+v (%v)", want, got, err)
+		}
+		if n, err := dbFor(c, app).NewSelect().Model((*Event)(nil)).Count(c); err != nil || n != 1 {
+			t.Fatalf("expected one %s row, got %d (%v)", want, n, err)
+		}
+	}
+
+	if err := Insert(UseConnection(ctx, "reporting"), app, &Event{Name: "x"}); err == nil {
+		t.Fatalf("expected an error for an unknown connection")
+	}
+}
@@ -0,0 +1,62 @@
+package flow
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestDBStatsEndpoint(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	var authed bool
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer ops" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			authed = true
+			next.ServeHTTP(w, r)
+		})
+	}
+	app := New("stats-test", WithDB(db), WithDBStats("", auth))
+	h := app.Handler()
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/dbstats", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without auth, got %d", rr.Code)
+	}
+
+	var one int
+	if err := db.QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/debug/dbstats", nil)
+	req.Header.Set("Authorization", "Bearer ops")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !authed {
+		t.Fatalf("expected 200 with auth, got %d", rr.Code)
+	}
+	var rep dbStatsReport
+	if err := json.NewDecoder(rr.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if rep.OpenConnections < 1 || rep.Idle < 1 {
+		t.Fatalf("expected an open idle connection after a query, got %+v", rep)
+	}
+	if rep.OpenConnections != app.DBStats().OpenConnections {
+		t.Fatalf("endpoint and DBStats disagree: %+v vs %+v", rep, app.DBStats())
+	}
+}
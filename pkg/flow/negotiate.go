@@ -0,0 +1,114 @@
+// Package flow: content negotiation.
+//
+// Negotiate lets one action serve several representations, picking the
+// one the client's Accept header prefers:
+//
+//	err := ctx.Negotiate(http.StatusOK,
+//		flow.Offer{ContentType: "text/html", Render: func() error { return ctx.Render("users/show", u) }},
+//		flow.Offer{ContentType: "application/json", Render: func() error { return ctx.JSON(http.StatusOK, u) }},
+//	)
+//	if err != nil {
+//		ctx.Fail(err)
+//	}
+package flow
+
+import (
+	"errors"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// ErrNotAcceptable is returned by Negotiate when the Accept header rules
+// out every offer. Fail responds 406.
+var ErrNotAcceptable = errors.New("flow: not acceptable")
+
+// Offer is a representation Negotiate can choose: its media type and the
+// function that writes it.
+type Offer struct {
+	ContentType string
+	Render      func() error
+}
+
+// acceptRange is one media range of an Accept header.
+type acceptRange struct {
+	typ, sub string
+	q        float64
+}
+
+// Negotiate runs the Render of the offer the Accept header prefers. Ranges
+// are weighed by their q value and a more specific range (text/html over
+// text/* over */*) decides an offer's weight; ties, a missing Accept header
+// and */* go to the earliest offer. status is used by views rendered from
+// the offer, as with RenderError. When every offer is excluded (no match,
+// or q=0) nothing is written and ErrNotAcceptable is returned. Vary: Accept
+// is always added.
+func (c *Context) Negotiate(status int, offers ...Offer) error {
+	c.AddVary("Accept")
+	ranges := parseAccept(c.R.Header.Get("Accept"))
+	best, bestQ := -1, 0.0
+	for i, o := range offers {
+		if q := acceptQuality(ranges, o.ContentType); q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best == -1 {
+		return ErrNotAcceptable
+	}
+	c.renderStatus = status
+	defer func() { c.renderStatus = 0 }()
+	return offers[best].Render()
+}
+
+// parseAccept parses an Accept header into media ranges. An empty header
+// accepts everything.
+func parseAccept(header string) []acceptRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptRange{{typ: "*", sub: "*", q: 1}}
+	}
+	var out []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, sub, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+		out = append(out, acceptRange{typ: typ, sub: sub, q: q})
+	}
+	return out
+}
+
+// acceptQuality returns the q value the most specific matching range gives
+// contentType, or 0 when no range matches.
+func acceptQuality(ranges []acceptRange, contentType string) float64 {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0
+	}
+	typ, sub, _ := strings.Cut(mt, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.sub == sub:
+			s = 2
+		case r.typ == typ && r.sub == "*":
+			s = 1
+		case r.typ == "*" && r.sub == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,65 @@
+package flow
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Negotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", "html"},
+		{"*/*", "html"},
+		{"application/json", "json"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html"},
+		{"text/html;q=0.5, application/json", "json"},
+		{"application/json;q=0.2, text/*;q=0.4", "html"},
+		{"text/*;q=0.9, text/html;q=0.1, application/json;q=0.5", "json"},
+		{"application/json, */*;q=0", "json"},
+		{"image/png", ""},
+		{"text/html;q=0, application/json;q=0", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rr := httptest.NewRecorder()
+		ctx := NewContext(nil, rr, req)
+		chosen := ""
+		err := ctx.Negotiate(http.StatusOK,
+			Offer{ContentType: "text/html; charset=utf-8", Render: func() error { chosen = "html"; return nil }},
+			Offer{ContentType: "application/json", Render: func() error { chosen = "json"; return nil }},
+		)
+		if tc.want == "" {
+			if !errors.Is(err, ErrNotAcceptable) {
+				t.Fatalf("Accept %q: expected ErrNotAcceptable, got %v (chose %q)", tc.accept, err, chosen)
+			}
+			continue
+		}
+		if err != nil || chosen != tc.want {
+			t.Fatalf("Accept %q: expected %s, got %q (%v)", tc.accept, tc.want, chosen, err)
+		}
+		if rr.Header().Get("Vary") != "Accept" {
+			t.Fatalf("expected Vary: Accept, got %q", rr.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestContext_NegotiateStatusForViews(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, req)
+	err := ctx.Negotiate(http.StatusCreated, Offer{ContentType: "text/html", Render: func() error {
+		ctx.writeHTMLHeader()
+		return nil
+	}})
+	if err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("expected the negotiated status for views, got %d (%v)", rr.Code, err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package flow: authorization policies.
+//
+// A policy (see `flow generate policy`) decides what a user may do with a
+// record; Authorize turns a denial into ErrForbidden so actions can hand it
+// to Context.Fail:
+//
+//	if err := flow.Authorize(policies.PostPolicy{}, "edit", user, post); err != nil {
+//		ctx.Fail(err) // 403
+//		return
+//	}
+package flow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden reports an action the user is not allowed to perform. Fail
+// responds 403.
+var ErrForbidden = errors.New("flow: forbidden")
+
+// Policy decides whether user may perform action (eg. "view", "edit",
+// "destroy") on record. Generated policies implement Can by dispatching to
+// their CanView, CanEdit and CanDestroy methods.
+type Policy interface {
+	Can(action string, user, record interface{}) bool
+}
+
+// Authorize returns nil when policy allows action, and an error wrapping
+// ErrForbidden otherwise. A nil policy denies everything.
+func Authorize(policy Policy, action string, user, record interface{}) error {
+	if policy == nil || !policy.Can(action, user, record) {
+		return fmt.Errorf("authorize %s: %w", action, ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package flow
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// ownerPolicy lets users edit only their own records.
+type ownerPolicy struct{}
+
+func (ownerPolicy) Can(action string, user, record interface{}) bool {
+	return action == "view" || user == record
+}
+
+func TestAuthorize(t *testing.T) {
+	if err := Authorize(ownerPolicy{}, "view", "bob", "alice"); err != nil {
+		t.Fatalf("expected view to be allowed, got %v", err)
+	}
+	if err := Authorize(ownerPolicy{}, "edit", "alice", "alice"); err != nil {
+		t.Fatalf("expected owner edit to be allowed, got %v", err)
+	}
+	err := Authorize(ownerPolicy{}, "edit", "bob", "alice")
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if got := (*App)(nil).ErrorStatus(err); got != http.StatusForbidden {
+		t.Fatalf("expected 403 for a denied action, got %d", got)
+	}
+	if err := Authorize(nil, "view", "bob", nil); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected a nil policy to deny, got %v", err)
+	}
+}
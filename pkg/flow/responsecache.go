@@ -0,0 +1,152 @@
+// Package flow: full-response caching.
+//
+// CacheResponse stores whole GET responses for a while and serves repeats
+// without running the handler, eg. for a public homepage:
+//
+//	r.GetWith("/", pages.Home, flow.CacheResponse(time.Minute, nil))
+//
+// Handlers opt out per response with Cache-Control: no-store (or private or
+// no-cache); requests with credentials are never cached. Responses that set
+// Vary (eg. Negotiate's Vary: Accept) are cached per value of the listed
+// request headers.
+package flow
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cachedResponse is a response stored by CacheResponse.
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// cachedVary is stored under a key whose response set Vary: the response
+// itself is stored under varyKey, per value of the listed request headers.
+type cachedVary struct {
+	fields []string
+}
+
+// CacheResponse caches the status, headers and body of GET responses for
+// ttl in a MemoryCache of its own; see CacheResponseWithStore.
+func CacheResponse(ttl time.Duration, keyFn func(*http.Request) string) Middleware {
+	return CacheResponseWithStore(NewMemoryCache(DefaultCacheSize), ttl, keyFn)
+}
+
+// CacheResponseWithStore caches GET responses in cache (eg. App.Cache) for
+// ttl. The first request for a key runs the handler and records its
+// response; later requests within ttl get the recorded response with
+// X-Cache: HIT and the handler is skipped. keyFn picks the key (the request
+// URI when nil); an empty key bypasses the cache, and so do requests with
+// credentials (an Authorization or Cookie header), whose responses may be
+// personal. Responses are not stored when they carry Cache-Control
+// no-store, no-cache or private, set a cookie, have Vary: * or a 5xx status.
+// A response with Vary is stored per value of the request headers it lists,
+// so eg. negotiated HTML and JSON are kept apart. Only
+// the headers the handler set are stored and replayed, so headers added by
+// outer middleware (eg. X-Request-ID) stay those of the current request.
+func CacheResponseWithStore(cache Cache, ttl time.Duration, keyFn func(*http.Request) string) Middleware {
+	if keyFn == nil {
+		keyFn = func(r *http.Request) string { return r.URL.RequestURI() }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || hasCredentials(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := keyFn(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = "response " + key
+			v, ok := cache.Get(key)
+			if vary, isVary := v.(*cachedVary); ok && isVary {
+				v, ok = cache.Get(varyKey(key, vary.fields, r))
+			}
+			if ok {
+				if resp, ok := v.(*cachedResponse); ok {
+					for k, v := range resp.header {
+						w.Header()[k] = slices.Clone(v)
+					}
+					w.Header().Set("X-Cache", "HIT")
+					w.WriteHeader(resp.status)
+					_, _ = w.Write(resp.body)
+					return
+				}
+			}
+
+			rec := newIdempotencyRecorder(w)
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+				rec.header = handlerHeaders(rec.before, w.Header())
+			}
+			if rec.status >= 500 || !cacheableHeader(w.Header()) {
+				return
+			}
+			resp := &cachedResponse{status: rec.status, header: rec.header, body: rec.body.Bytes()}
+			if fields := varyFields(w.Header()); len(fields) > 0 {
+				cache.Set(key, &cachedVary{fields: fields}, ttl)
+				key = varyKey(key, fields, r)
+			}
+			cache.Set(key, resp, ttl)
+		})
+	}
+}
+
+// hasCredentials reports whether r carries an Authorization or Cookie
+// header.
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+// varyFields returns the canonical request header names listed in h's Vary
+// header, sorted and without duplicates.
+func varyFields(h http.Header) []string {
+	var fields []string
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, http.CanonicalHeaderKey(f))
+			}
+		}
+	}
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
+
+// varyKey extends key with r's values of the request headers in fields.
+func varyKey(key string, fields []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, f := range fields {
+		b.WriteString("\x00")
+		b.WriteString(f)
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Header.Values(f), ","))
+	}
+	return b.String()
+}
+
+// cacheableHeader reports whether a response with header h may be stored.
+func cacheableHeader(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 || slices.Contains(varyFields(h), "*") {
+		return false
+	}
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "no-store", "no-cache", "private":
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package flow
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheResponse(t *testing.T) {
+	var runs int32
+	h := CacheResponse(time.Minute, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&runs, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "page %d", n)
+	}))
+	get := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	first, second := get("GET", "/"), get("GET", "/")
+	if runs != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", runs)
+	}
+	if second.Code != http.StatusOK || second.Body.String() != "page 1" || second.Header().Get("Content-Type") != "text/html" {
+		t.Fatalf("expected the cached response, got %d %q %v", second.Code, second.Body.String(), second.Header())
+	}
+	if first.Header().Get("X-Cache") != "" || second.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("X-Cache: first %q, second %q", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+
+	if got := get("GET", "/?page=2").Body.String(); got != "page 2" {
+		t.Fatalf("expected a separate entry per URI, got %q", got)
+	}
+	get("GET", "/private")
+	if got := get("GET", "/private").Body.String(); got != "page 4" {
+		t.Fatalf("expected no-store responses to bypass the cache, got %q", got)
+	}
+	if got := get("POST", "/").Body.String(); got != "page 5" {
+		t.Fatalf("expected POST to bypass the cache, got %q", got)
+	}
+}
+
+func TestCacheResponse_Expires(t *testing.T) {
+	var runs int32
+	h := CacheResponse(20*time.Millisecond, func(r *http.Request) string { return r.URL.Path })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&runs, 1)
+	}))
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	time.Sleep(40 * time.Millisecond)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if runs != 2 {
+		t.Fatalf("expected the handler to run again after the ttl, ran %d times", runs)
+	}
+}
+
+func TestCacheResponse_PrivateResponses(t *testing.T) {
+	var runs int32
+	h := CacheResponse(time.Minute, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&runs, 1)
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/revalidate":
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		fmt.Fprintf(w, "page %d", n)
+	}))
+	get := func(path string, header http.Header) string {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	for _, path := range []string{"/private", "/revalidate"} {
+		first, second := get(path, nil), get(path, nil)
+		if first == second {
+			t.Fatalf("%s: expected the response not to be cached, got %q twice", path, first)
+		}
+	}
+	for _, hdr := range []http.Header{
+		{"Authorization": {"Bearer alice"}},
+		{"Cookie": {"session=alice"}},
+	} {
+		first := get("/account", hdr)
+		if second := get("/account", nil); second == first {
+			t.Fatalf("response to a request with %v was served to another client: %q", hdr, second)
+		}
+	}
+}
+
+func TestCacheResponse_KeepsOuterHeaders(t *testing.T) {
+	var n int32
+	requestID := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-ID", fmt.Sprintf("req-%d", atomic.AddInt32(&n, 1)))
+			next.ServeHTTP(w, r)
+		})
+	}
+	h := requestID(CacheResponse(time.Minute, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "page")
+	})))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Header().Get("X-Cache") != "HIT" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("expected a cache hit with the handler's headers, got %v", rec.Header())
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "req-2" {
+		t.Fatalf("cached response overwrote the current X-Request-ID: %q", got)
+	}
+}
+
+func TestCacheResponse_Vary(t *testing.T) {
+	app := New("testapp")
+	var runs int32
+	h := CacheResponse(time.Minute, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&runs, 1)
+		ctx := NewContext(app, w, r)
+		if r.URL.Path == "/hx" {
+			ctx.AddVary("HX-Request")
+			if r.Header.Get("HX-Request") == "true" {
+				fmt.Fprint(w, "fragment")
+				return
+			}
+			fmt.Fprint(w, "page")
+			return
+		}
+		_ = ctx.Negotiate(http.StatusOK,
+			Offer{ContentType: "text/html", Render: func() error { fmt.Fprint(w, "<p>hi</p>"); return nil }},
+			Offer{ContentType: "application/json", Render: func() error { return ctx.JSON(http.StatusOK, "hi") }},
+		)
+	}))
+	get := func(path, name, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct{ path, header, a, b, wantA, wantB string }{
+		{"/hx", "HX-Request", "", "true", "page", "fragment"},
+		{"/negotiate", "Accept", "text/html", "application/json", "<p>hi</p>", "\"hi\"\n"},
+	} {
+		before := atomic.LoadInt32(&runs)
+		if got := get(tc.path, tc.header, tc.a).Body.String(); got != tc.wantA {
+			t.Fatalf("%s %s=%q: got %q", tc.path, tc.header, tc.a, got)
+		}
+		// same URI, different varied header: must not be served from cache
+		if got := get(tc.path, tc.header, tc.b).Body.String(); got != tc.wantB {
+			t.Fatalf("%s %s=%q: got %q, want %q", tc.path, tc.header, tc.b, got, tc.wantB)
+		}
+		for _, v := range []struct{ value, want string }{{tc.a, tc.wantA}, {tc.b, tc.wantB}} {
+			rec := get(tc.path, tc.header, v.value)
+			if rec.Header().Get("X-Cache") != "HIT" || rec.Body.String() != v.want {
+				t.Fatalf("%s %s=%q: expected cached %q, got %q (%v)", tc.path, tc.header, v.value, v.want, rec.Body.String(), rec.Header())
+			}
+		}
+		if n := atomic.LoadInt32(&runs) - before; n != 2 {
+			t.Fatalf("%s: expected one handler run per variant, got %d", tc.path, n)
+		}
+	}
+}
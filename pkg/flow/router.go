@@ -9,6 +9,7 @@ package flow
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 
 	routerpkg "github.com/dministrator/flow/internal/router"
 )
@@ -25,7 +26,12 @@ type Router struct {
 // for tests, but Resource adapters that need App will require a non-nil
 // App to function correctly.
 func NewRouter(app *App) *Router {
-	return &Router{inner: routerpkg.New(), app: app}
+	inner := routerpkg.New()
+	if app != nil {
+		// unmatched requests get the App's (JSON by default) 404
+		inner.NotFound = http.HandlerFunc(app.serveNotFound)
+	}
+	return &Router{inner: inner, app: app}
 }
 
 // Get registers a GET handler that accepts a *flow.Context for the given pattern.
@@ -138,6 +144,90 @@ func (r *Router) DeleteWith(pattern string, h func(*Context), mws ...Middleware)
 	r.inner.DeleteWith(pattern, wrapped, conv...)
 }
 
+// wrap adapts a Context handler to an http.HandlerFunc bound to the App.
+func (r *Router) wrap(h func(*Context)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		h(NewContext(r.app, w, req))
+	}
+}
+
+// HandleExact registers a route that keeps its trailing slash significant:
+// HandleExact("GET", "/dir/", h) matches "/dir/" but not "/dir". Register
+// it before any normalized route for the same path, since routes match in
+// order.
+func (r *Router) HandleExact(method, pattern string, h func(*Context)) {
+	r.inner.HandleExact(method, pattern, r.wrap(h))
+}
+
+// GetNamed registers a named GET route. Names must be unique (a duplicate
+// panics) and are used with URL to build paths.
+func (r *Router) GetNamed(name, pattern string, h func(*Context)) {
+	r.inner.GetNamed(name, pattern, r.wrap(h))
+}
+
+// PostNamed registers a named POST route.
+func (r *Router) PostNamed(name, pattern string, h func(*Context)) {
+	r.inner.PostNamed(name, pattern, r.wrap(h))
+}
+
+// PutNamed registers a named PUT route.
+func (r *Router) PutNamed(name, pattern string, h func(*Context)) {
+	r.inner.PutNamed(name, pattern, r.wrap(h))
+}
+
+// PatchNamed registers a named PATCH route.
+func (r *Router) PatchNamed(name, pattern string, h func(*Context)) {
+	r.inner.PatchNamed(name, pattern, r.wrap(h))
+}
+
+// DeleteNamed registers a named DELETE route.
+func (r *Router) DeleteNamed(name, pattern string, h func(*Context)) {
+	r.inner.DeleteNamed(name, pattern, r.wrap(h))
+}
+
+// URL builds the path of the named route, filling :param segments from
+// params, eg. URL("users_show", map[string]string{"id": "7"}) gives
+// "/users/7". Resources registers <base>_index, _new, _create, _show,
+// _edit, _update, _patch and _destroy.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	return r.inner.URL(name, params)
+}
+
+// URLWithQuery builds the named route's path like URL and appends the
+// encoded query, eg. for pagination links ("/users?page=2"). Extra params
+// keys never become query parameters; only query is appended.
+func (r *Router) URLWithQuery(name string, params map[string]string, query url.Values) (string, error) {
+	return r.inner.URLWithQuery(name, params, query)
+}
+
+// Static serves the files under dir at urlPrefix (GET and HEAD), eg.
+// r.Static("/static", "public") for CSS and JS assets. Missing files get
+// the router's 404.
+func (r *Router) Static(urlPrefix, dir string) { r.inner.Static(urlPrefix, dir) }
+
+// StaticConfig configures StaticWith: an index file for directory paths,
+// an Authorize hook (false answers 403) and extra headers per extension.
+type StaticConfig = routerpkg.StaticConfig
+
+// StaticWith is Static configured by cfg, eg. to serve index.html for
+// directories or add Cache-Control to ".js" files. Directories are never
+// listed.
+func (r *Router) StaticWith(urlPrefix, dir string, cfg StaticConfig) {
+	r.inner.StaticWith(urlPrefix, dir, cfg)
+}
+
+// Mount delegates requests under prefix to sub with the prefix stripped,
+// eg. r.Mount("/api", apiRouter). Params captured by the prefix
+// ("/orgs/:org") remain visible to sub's handlers.
+func (r *Router) Mount(prefix string, sub http.Handler) { r.inner.Mount(prefix, sub) }
+
+// RouteInfo describes a registered route: method, pattern and name.
+type RouteInfo = routerpkg.RouteInfo
+
+// Routes lists the registered routes in matching order, eg. for a debug
+// page or a routes listing.
+func (r *Router) Routes() []RouteInfo { return r.inner.Routes() }
+
 // Resources wires a flow.Resource into RESTful routes using the conventional
 // path base. It uses MakeResourceAdapter to adapt the Resource to the
 // internal router.ResourceController.
@@ -148,6 +238,43 @@ func (r *Router) Resources(base string, res Resource) error {
 	return r.inner.Resources(base, MakeResourceAdapter(r.app, res))
 }
 
+// Resource wires a flow.Resource into the routes of a singleton resource
+// (eg. "profile": GET /profile, GET /profile/edit, ...) without an :id
+// segment. Index is not routed.
+func (r *Router) Resource(base string, res Resource) error {
+	if r.app == nil {
+		return fmt.Errorf("router: cannot register resources without an App; provide an App to NewRouter")
+	}
+	return r.inner.Resource(base, MakeResourceAdapter(r.app, res))
+}
+
+// SetPluralize makes Resources pluralize its base (Resources("user", ...)
+// serves /users). It is off by default, using the base as given.
+func (r *Router) SetPluralize(on bool) { r.inner.SetPluralize(on) }
+
+// SetAutoOptions makes OPTIONS requests to known paths return 204 with an
+// Allow header listing the registered methods (405 responses always carry
+// Allow).
+func (r *Router) SetAutoOptions(on bool) { r.inner.AutoOptions = on }
+
+// SetRedirectTrailingSlash makes "/users/" redirect to "/users" (301 for
+// GET/HEAD, 308 otherwise) instead of being served as the same route.
+func (r *Router) SetRedirectTrailingSlash(on bool) { r.inner.RedirectTrailingSlash = on }
+
+// Use registers middleware for every route on this Router, including
+// Resources routes. Router middleware runs inside App middleware (see
+// App.Use) and outside per-route middleware passed to the With variants.
+func (r *Router) Use(mws ...Middleware) {
+	for _, mw := range mws {
+		r.inner.Use(routerpkg.Middleware(mw))
+	}
+}
+
+// SetBasePath configures a global prefix for apps served under a sub-path
+// (eg. "/app"). Requests outside the prefix are not found, the prefix is
+// stripped before matching, and generated route URLs include it.
+func (r *Router) SetBasePath(prefix string) { r.inner.SetBasePath(prefix) }
+
 // ServeHTTP forwards to the internal router's ServeHTTP implementation.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.inner.ServeHTTP(w, req)
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -70,3 +71,103 @@ func TestPublicRouterIntegration(t *testing.T) {
 		t.Fatalf("expected 405 for POST /hello, got %d", rr.Code)
 	}
 }
+
+func TestRouterUse_MiddlewareOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	app := New("test-app")
+	app.Use(tag("app"))
+	r := NewRouter(app)
+	r.Use(tag("router1"), tag("router2"))
+	r.GetWith("/ping", func(ctx *Context) { order = append(order, "handler") }, tag("route"))
+
+	var routeName string
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			routeName = NewContext(app, w, req).RouteName()
+			next.ServeHTTP(w, req)
+		})
+	})
+	if err := r.Resources("users", NewUsersController(app)); err != nil {
+		t.Fatalf("resources: %v", err)
+	}
+	app.SetRouter(r)
+	h := app.Handler()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+	if got := strings.Join(order, ","); got != "app,router1,router2,route,handler" {
+		t.Fatalf("unexpected middleware order: %s", got)
+	}
+
+	order = nil
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/3", nil))
+	if got := strings.Join(order, ","); got != "app,router1,router2" {
+		t.Fatalf("unexpected middleware order for resource route: %s", got)
+	}
+	if routeName != "users_show" {
+		t.Fatalf("expected router middleware to see route name users_show, got %q", routeName)
+	}
+}
+
+func TestPublicRouterNamedRoutesAndURL(t *testing.T) {
+	app := New("test-app")
+	r := NewRouter(app)
+	r.GetNamed("post_comment", "/posts/:post_id/comments/:id", func(ctx *Context) {
+		_, _ = ctx.W.Write([]byte(ctx.RouteName()))
+	})
+	if err := r.Resources("users", NewUsersController(app)); err != nil {
+		t.Fatalf("resources: %v", err)
+	}
+
+	p, err := r.URL("post_comment", map[string]string{"post_id": "3", "id": "9"})
+	if err != nil || p != "/posts/3/comments/9" {
+		t.Fatalf("expected /posts/3/comments/9, got %q, %v", p, err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", p, nil))
+	if rr.Body.String() != "post_comment" {
+		t.Fatalf("expected named route to serve, got %q", rr.Body.String())
+	}
+
+	for name, want := range map[string]string{
+		"users_index": "/users",
+		"users_new":   "/users/new",
+		"users_show":  "/users/7",
+		"users_edit":  "/users/7/edit",
+	} {
+		got, err := r.URL(name, map[string]string{"id": "7"})
+		if err != nil || got != want {
+			t.Errorf("URL(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := r.URL("missing", nil); err == nil {
+		t.Fatalf("expected error for unknown route name")
+	}
+}
+
+func TestRouterRoutes(t *testing.T) {
+	app := New("routes-app")
+	r := NewRouter(app)
+	r.GetNamed("about", "/about", func(ctx *Context) {})
+	if err := r.Resources("users", NewUsersController(app)); err != nil {
+		t.Fatalf("Resources error: %v", err)
+	}
+	routes := r.Routes()
+	if len(routes) != 9 {
+		t.Fatalf("expected 9 routes (about + 8 resource routes), got %d: %+v", len(routes), routes)
+	}
+	if routes[0] != (RouteInfo{Method: "GET", Pattern: "/about", Name: "about"}) {
+		t.Fatalf("unexpected first route: %+v", routes[0])
+	}
+	if routes[1].Name != "users_index" || routes[8].Name != "users_destroy" {
+		t.Fatalf("unexpected resource route order: %+v", routes)
+	}
+}
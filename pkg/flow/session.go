@@ -114,12 +114,27 @@ func (s *Session) Delete(key string) error {
 	return s.Save()
 }
 
-// Save encodes the session and sets the cookie.
+// Save encodes the session and sets the cookie, replacing a session
+// cookie set earlier in the same response.
 func (s *Session) Save() error {
 	enc, err := s.sm.encodeForCookie(s.values)
 	if err != nil {
 		return err
 	}
+	h := s.w.Header()
+	if prev := h.Values("Set-Cookie"); len(prev) > 0 {
+		kept := prev[:0:0]
+		for _, c := range prev {
+			if !strings.HasPrefix(c, s.sm.CookieName+"=") {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == 0 {
+			h.Del("Set-Cookie")
+		} else {
+			h["Set-Cookie"] = kept
+		}
+	}
 	cookie := &http.Cookie{
 		Name:     s.sm.CookieName,
 		Value:    enc,
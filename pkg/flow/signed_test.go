@@ -0,0 +1,68 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApp_SignedValues(t *testing.T) {
+	app := New("signed-test")
+
+	token := app.SignValue("confirm", "ada@example.com", time.Hour)
+	if got, err := app.VerifySignedValue("confirm", token); err != nil || got != "ada@example.com" {
+		t.Fatalf("expected round-trip, got %q, %v", got, err)
+	}
+	forever := app.SignValue("confirm", "", 0)
+	if got, err := app.VerifySignedValue("confirm", forever); err != nil || got != "" {
+		t.Fatalf("expected a token without expiry to verify, got %q, %v", got, err)
+	}
+
+	expired := app.signValue("confirm", "ada@example.com", time.Now().Add(-time.Minute))
+	if _, err := app.VerifySignedValue("confirm", expired); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected ErrSignatureExpired, got %v", err)
+	}
+
+	// a different value, expiry or purpose does not verify
+	value, rest, _ := strings.Cut(token, ".")
+	tampered := []string{
+		"Ym9iQGV4YW1wbGUuY29t." + rest,
+		value + ".9999999999." + token[strings.LastIndexByte(token, '.')+1:],
+		token + "x",
+		"",
+		"garbage",
+	}
+	for _, tok := range tampered {
+		if _, err := app.VerifySignedValue("confirm", tok); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("expected ErrSignatureInvalid for %q, got %v", tok, err)
+		}
+	}
+	if _, err := app.VerifySignedValue("reset", token); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected a confirm token to be rejected as a reset token, got %v", err)
+	}
+	if _, err := New("other-secret").VerifySignedValue("confirm", token); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected a token from another secret to be rejected, got %v", err)
+	}
+}
+
+func TestContext_SignedQuery(t *testing.T) {
+	app := New("signed-test")
+	token := app.SignValue("confirm", "42", time.Hour)
+	req := httptest.NewRequest("GET", "/confirm?token="+url.QueryEscape(token), nil)
+	ctx := NewContext(app, httptest.NewRecorder(), req)
+	if got, err := ctx.SignedQuery("confirm", "token"); err != nil || got != "42" {
+		t.Fatalf("expected 42, got %q, %v", got, err)
+	}
+	if _, err := ctx.SignedQuery("confirm", "missing"); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected ErrSignatureInvalid for a missing token, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewContext(app, rec, req).Fail(ErrSignatureExpired)
+	if rec.Code != 400 {
+		t.Fatalf("expected 400 from Fail, got %d", rec.Code)
+	}
+}
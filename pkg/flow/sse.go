@@ -0,0 +1,84 @@
+// Package flow: Server-Sent Events and streamed responses.
+//
+// SSE pushes one event per call, so a handler can keep the connection open
+// and send updates as they happen:
+//
+//	ctx.Stream(func(w io.Writer) bool {
+//		select {
+//		case m := <-metrics:
+//			return ctx.SSE("metrics", m) == nil
+//		case <-time.After(15 * time.Second):
+//			return ctx.SSE("ping", nil) == nil
+//		}
+//	})
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrStreamingUnsupported is returned by SSE when the ResponseWriter cannot
+// flush, so events would sit in a buffer instead of reaching the client.
+var ErrStreamingUnsupported = errors.New("flow: response writer does not support flushing")
+
+// SSE writes one Server-Sent Event with data encoded as JSON and flushes it
+// to the client. An empty event sends a data-only message (the "message"
+// event in browsers). The first call sets Content-Type: text/event-stream,
+// disables caching and writes 200.
+func (c *Context) SSE(event string, data interface{}) error {
+	flusher, ok := c.W.(http.Flusher)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	if strings.ContainsAny(event, "\r\n") {
+		return fmt.Errorf("sse: event name %q contains a line break", event)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("sse: %w", err)
+	}
+	if c.status == 0 {
+		c.SetHeader("Content-Type", "text/event-stream")
+		c.SetHeader("Cache-Control", "no-cache")
+		c.SetHeader("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+	}
+	var msg strings.Builder
+	if event != "" {
+		msg.WriteString("event: " + event + "\n")
+	}
+	msg.WriteString("data: " + string(b) + "\n\n")
+	if _, err := io.WriteString(c.W, msg.String()); err != nil {
+		return fmt.Errorf("sse: %w", err)
+	}
+	flusher.Flush()
+	return nil
+}
+
+// Stream calls fn repeatedly, flushing after each call when the
+// ResponseWriter supports it, until fn returns false or the client goes
+// away (the request context is done). fn may block waiting for data; it
+// should also watch c.R.Context().Done() to return promptly on disconnect.
+func (c *Context) Stream(fn func(w io.Writer) bool) {
+	flusher, _ := c.W.(http.Flusher)
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		keepOpen := fn(c.W)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !keepOpen {
+			return
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package flow
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContext_SSE(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/events", nil))
+
+	if err := ctx.SSE("update", map[string]int{"count": 1}); err != nil {
+		t.Fatalf("sse: %v", err)
+	}
+	if err := ctx.SSE("", "hi"); err != nil {
+		t.Fatalf("sse: %v", err)
+	}
+	want := "event: update\ndata: {\"count\":1}\n\ndata: \"hi\"\n\n"
+	if got := rr.Body.String(); got != want {
+		t.Fatalf("unexpected stream:\n got %q\nwant %q", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("unexpected cache control %q", cc)
+	}
+	if !rr.Flushed {
+		t.Fatalf("expected the recorder to be flushed")
+	}
+	if err := ctx.SSE("bad\nevent", 1); err == nil {
+		t.Fatalf("expected an error for an event name with a newline")
+	}
+
+	// a writer without http.Flusher cannot stream
+	w := struct{ http.ResponseWriter }{httptest.NewRecorder()}
+	ctx = NewContext(nil, w, httptest.NewRequest("GET", "/events", nil))
+	if err := ctx.SSE("update", 1); !errors.Is(err, ErrStreamingUnsupported) {
+		t.Fatalf("expected ErrStreamingUnsupported, got %v", err)
+	}
+}
+
+func TestContext_SSEFlushesEachEvent(t *testing.T) {
+	next := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(nil, w, r)
+		for i := 0; i < 2; i++ {
+			if err := ctx.SSE("tick", i); err != nil {
+				t.Errorf("sse: %v", err)
+				return
+			}
+			// the next event is only sent once the client read this one
+			<-next
+		}
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	for i, want := range []string{"event: tick\ndata: 0\n\n", "event: tick\ndata: 1\n\n"} {
+		var got strings.Builder
+		for !strings.HasSuffix(got.String(), "\n\n") {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("event %d: %v", i, err)
+			}
+			got.WriteString(line)
+		}
+		if got.String() != want {
+			t.Fatalf("event %d: got %q, want %q", i, got.String(), want)
+		}
+		next <- struct{}{}
+	}
+}
+
+func TestContext_Stream(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ctx := NewContext(nil, rr, httptest.NewRequest("GET", "/stream", nil))
+	n := 0
+	ctx.Stream(func(w io.Writer) bool {
+		n++
+		io.WriteString(w, "chunk\n")
+		return n < 3
+	})
+	if n != 3 || rr.Body.String() != "chunk\nchunk\nchunk\n" || !rr.Flushed {
+		t.Fatalf("unexpected stream: %d calls, %q, flushed %v", n, rr.Body.String(), rr.Flushed)
+	}
+
+	// stops once the client has gone away
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/stream", nil).WithContext(reqCtx)
+	ctx = NewContext(nil, httptest.NewRecorder(), req)
+	n = 0
+	ctx.Stream(func(w io.Writer) bool {
+		n++
+		if n == 2 {
+			cancel()
+		}
+		return true
+	})
+	if n != 2 {
+		t.Fatalf("expected the stream to stop after the disconnect, got %d calls", n)
+	}
+}
@@ -0,0 +1,94 @@
+// Package flow: application tasks.
+//
+// Tasks are one-off jobs (eg. "recalculate_stats") that app code registers
+// from init functions, usually in app/tasks (see `flow generate task`):
+//
+//	func init() {
+//		flow.RegisterTask("recalculate_stats", func(ctx context.Context, args []string) error {
+//			...
+//		})
+//	}
+//
+// The project's cmd/tasks program passes its arguments to TaskMain, and
+// `flow task <name> [args]` runs that program.
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// TaskFunc runs a task with the arguments given after its name.
+type TaskFunc func(ctx context.Context, args []string) error
+
+// ErrUnknownTask is returned by RunTask for a name nothing registered.
+var ErrUnknownTask = errors.New("flow: unknown task")
+
+var (
+	tasksMu sync.RWMutex
+	tasks   = map[string]TaskFunc{}
+)
+
+// RegisterTask makes fn available as the task name. It panics when name is
+// empty, fn is nil or name is already registered, like duplicate routes.
+func RegisterTask(name string, fn TaskFunc) {
+	if name == "" || fn == nil {
+		panic("flow: RegisterTask needs a name and a function")
+	}
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if _, dup := tasks[name]; dup {
+		panic(fmt.Sprintf("flow: task %q registered twice", name))
+	}
+	tasks[name] = fn
+}
+
+// TaskNames returns the registered task names, sorted.
+func TaskNames() []string {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunTask runs the task registered as name with args. An unregistered name
+// yields an error wrapping ErrUnknownTask.
+func RunTask(ctx context.Context, name string, args []string) error {
+	tasksMu.RLock()
+	fn, ok := tasks[name]
+	tasksMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("task %s: %w", name, ErrUnknownTask)
+	}
+	if err := fn(ctx, args); err != nil {
+		return fmt.Errorf("task %s: %w", name, err)
+	}
+	return nil
+}
+
+// TaskMain implements a task runner's command line: with no arguments it
+// lists the registered tasks on out, otherwise it runs the task named by
+// args[0] with the remaining arguments.
+func TaskMain(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		names := TaskNames()
+		if len(names) == 0 {
+			fmt.Fprintln(out, "no tasks registered")
+			return nil
+		}
+		fmt.Fprintln(out, "available tasks:")
+		for _, name := range names {
+			fmt.Fprintln(out, "  "+name)
+		}
+		return nil
+	}
+	return RunTask(ctx, args[0], args[1:])
+}
@@ -0,0 +1,45 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTasks(t *testing.T) {
+	var got []string
+	RegisterTask("test_echo", func(ctx context.Context, args []string) error {
+		got = args
+		return nil
+	})
+	RegisterTask("test_fail", func(ctx context.Context, args []string) error {
+		return errors.New("boom")
+	})
+
+	var out bytes.Buffer
+	if err := TaskMain(context.Background(), nil, &out); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(out.String(), "  test_echo\n  test_fail\n") {
+		t.Fatalf("expected sorted task list, got %q", out.String())
+	}
+
+	if err := TaskMain(context.Background(), []string{"test_echo", "a", "b"}, &out); err != nil || strings.Join(got, ",") != "a,b" {
+		t.Fatalf("expected args a,b, got %v (%v)", got, err)
+	}
+	if err := RunTask(context.Background(), "test_fail", nil); err == nil || err.Error() != "task test_fail: boom" {
+		t.Fatalf("expected the task error, got %v", err)
+	}
+	if err := RunTask(context.Background(), "missing", nil); !errors.Is(err, ErrUnknownTask) {
+		t.Fatalf("expected ErrUnknownTask, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for a duplicate task")
+		}
+	}()
+	RegisterTask("test_echo", func(context.Context, []string) error { return nil })
+}
@@ -0,0 +1,166 @@
+// Package flow: multipart uploads.
+//
+// FormFile and SaveUploadedFile cover the common case of a form with a
+// file field:
+//
+//	fh, err := ctx.FormFile("avatar")
+//	if err == nil {
+//		err = ctx.SaveUploadedFile(fh, filepath.Join("uploads", fh.Filename))
+//	}
+//
+// ParseMultipartForm buffers uploads in memory (spilling to temp files), so
+// very large files are read twice. MultipartReader hands the handler the
+// parts one at a time instead, and StreamUpload copies a single file part
+// straight to disk:
+//
+//	n, err := ctx.StreamUpload("video", filepath.Join(dir, "upload.mp4"))
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DefaultMaxUploadBytes is the total multipart body size accepted by
+// MultipartReader and StreamUpload unless WithMaxUploadBytes says otherwise.
+const DefaultMaxUploadBytes = 1 << 30
+
+// DefaultMultipartMemory is how much of a multipart form FormFile and
+// BindForm hold in memory before file parts spill to temporary files,
+// unless WithMultipartMemory says otherwise.
+const DefaultMultipartMemory = 32 << 20
+
+// ErrMissingUpload is returned by StreamUpload and FormFile when the
+// request has no part for the requested field. Context.Fail maps it to 400.
+var ErrMissingUpload = errors.New("flow: upload field not found")
+
+// ErrEmptyUpload is returned by FormFile and SaveUploadedFile for a
+// zero-byte file. Context.Fail maps it to 400.
+var ErrEmptyUpload = errors.New("flow: uploaded file is empty")
+
+// multipartMemory returns the App's multipart memory limit.
+func (c *Context) multipartMemory() int64 {
+	if c.App != nil && c.App.multipartMemory > 0 {
+		return c.App.multipartMemory
+	}
+	return DefaultMultipartMemory
+}
+
+// FormFile parses the multipart form (keeping up to the App's multipart
+// memory in RAM, the rest in temporary files) and returns the header of
+// the first file uploaded as name. A missing field yields
+// ErrMissingUpload and a zero-byte file ErrEmptyUpload.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if name == "" {
+		return nil, errors.New("upload: empty field name")
+	}
+	if c.R.MultipartForm == nil {
+		if err := c.R.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return nil, uploadErr(err)
+		}
+	}
+	fhs := c.R.MultipartForm.File[name]
+	if len(fhs) == 0 {
+		return nil, ErrMissingUpload
+	}
+	if fhs[0].Size == 0 {
+		return nil, ErrEmptyUpload
+	}
+	return fhs[0], nil
+}
+
+// SaveUploadedFile copies the uploaded file fh to dst, creating missing
+// parent directories. An existing dst is overwritten; on failure the
+// partial file is removed.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	if fh == nil || fh.Size == 0 {
+		return ErrEmptyUpload
+	}
+	if dst == "" {
+		return errors.New("upload: empty destination")
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	_, err = writeUpload(dst, src)
+	return err
+}
+
+// MultipartReader returns a streaming reader over the multipart request
+// body so parts can be processed incrementally. The body is capped at the
+// App's upload limit; reading past it fails with ErrBodyTooLarge.
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	limit := int64(DefaultMaxUploadBytes)
+	if c.App != nil && c.App.maxUploadBytes > 0 {
+		limit = c.App.maxUploadBytes
+	}
+	c.R.Body = http.MaxBytesReader(c.W, c.R.Body, limit)
+	mr, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	return mr, nil
+}
+
+// StreamUpload copies the first part named field to the file dst, without
+// buffering the upload, and returns the number of bytes written. Parts
+// before it are skipped. If the body exceeds the upload limit the partial
+// file is removed and ErrBodyTooLarge returned; a missing field yields
+// ErrMissingUpload.
+func (c *Context) StreamUpload(field, dst string) (int64, error) {
+	mr, err := c.MultipartReader()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return 0, ErrMissingUpload
+		}
+		if err != nil {
+			return 0, uploadErr(err)
+		}
+		if part.FormName() != field {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+		return writeUpload(dst, part)
+	}
+}
+
+// writeUpload copies r to a new file at dst, removing it on failure.
+func writeUpload(dst string, r io.Reader) (int64, error) {
+	f, err := os.Create(dst)
+	if err != nil {
+		return 0, fmt.Errorf("upload: %w", err)
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return n, uploadErr(err)
+	}
+	return n, nil
+}
+
+// uploadErr maps body size errors to ErrBodyTooLarge.
+func uploadErr(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return ErrBodyTooLarge
+	}
+	return fmt.Errorf("upload: %w", err)
+}
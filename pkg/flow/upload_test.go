@@ -0,0 +1,159 @@
+package flow
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// multipartBody streams a multipart body with a small "note" field followed
+// by a file part of size bytes, generated chunk by chunk.
+func multipartBody(t *testing.T, field string, size int) (io.Reader, string) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		_ = mw.WriteField("note", "hello")
+		fw, err := mw.CreateFormFile(field, "big.bin")
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		chunk := bytes.Repeat([]byte("x"), 64<<10)
+		for written := 0; written < size; written += len(chunk) {
+			if size-written < len(chunk) {
+				chunk = chunk[:size-written]
+			}
+			if _, err := fw.Write(chunk); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+	return pr, mw.FormDataContentType()
+}
+
+func TestContext_StreamUpload(t *testing.T) {
+	const size = 16 << 20
+	body, ct := multipartBody(t, "file", size)
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	ctx := NewContext(New("upload-test"), httptest.NewRecorder(), req)
+	dst := filepath.Join(t.TempDir(), "big.bin")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	n, err := ctx.StreamUpload("file", dst)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("stream upload: %v", err)
+	}
+	if n != size {
+		t.Fatalf("expected %d bytes, got %d", size, n)
+	}
+	if fi, err := os.Stat(dst); err != nil || fi.Size() != size {
+		t.Fatalf("unexpected file on disk: %v, %v", fi, err)
+	}
+	// the part is copied in small chunks, never held in memory whole
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/4 {
+		t.Fatalf("upload allocated %d bytes for a %d byte part", alloc, size)
+	}
+}
+
+func TestContext_StreamUploadLimits(t *testing.T) {
+	body, ct := multipartBody(t, "file", 64<<10)
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	ctx := NewContext(New("upload-test", WithMaxUploadBytes(4<<10)), httptest.NewRecorder(), req)
+	dst := filepath.Join(t.TempDir(), "big.bin")
+	if _, err := ctx.StreamUpload("file", dst); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("expected partial file to be removed, stat err: %v", err)
+	}
+
+	body, ct = multipartBody(t, "file", 1<<10)
+	req = httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	ctx = NewContext(New("upload-test"), httptest.NewRecorder(), req)
+	if _, err := ctx.StreamUpload("avatar", dst); !errors.Is(err, ErrMissingUpload) {
+		t.Fatalf("expected ErrMissingUpload, got %v", err)
+	}
+}
+
+// formWithFile builds a multipart request with a "title" field and a file
+// part named field holding content.
+func formWithFile(t *testing.T, field, filename string, content []byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("title", "report")
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestContext_FormFileAndSave(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	// a tiny memory limit makes the file spill to a temporary file
+	app := New("upload-test", WithMultipartMemory(1<<10))
+	ctx := NewContext(app, httptest.NewRecorder(), formWithFile(t, "doc", "report.txt", content))
+
+	fh, err := ctx.FormFile("doc")
+	if err != nil {
+		t.Fatalf("form file: %v", err)
+	}
+	if fh.Filename != "report.txt" || fh.Size != int64(len(content)) {
+		t.Fatalf("unexpected header: %q, %d bytes", fh.Filename, fh.Size)
+	}
+	if ctx.FormValue("title") != "report" {
+		t.Fatalf("expected other form fields to be parsed, got %q", ctx.FormValue("title"))
+	}
+
+	dst := filepath.Join(t.TempDir(), "nested", "dir", fh.Filename)
+	if err := ctx.SaveUploadedFile(fh, dst); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("saved file differs (%d bytes, %v)", len(got), err)
+	}
+
+	if _, err := ctx.FormFile("missing"); !errors.Is(err, ErrMissingUpload) {
+		t.Fatalf("expected ErrMissingUpload, got %v", err)
+	}
+	if _, err := ctx.FormFile(""); err == nil {
+		t.Fatalf("expected an error for an empty field name")
+	}
+}
+
+func TestContext_FormFileRejectsEmptyFiles(t *testing.T) {
+	ctx := NewContext(New("upload-test"), httptest.NewRecorder(), formWithFile(t, "doc", "empty.txt", nil))
+	if _, err := ctx.FormFile("doc"); !errors.Is(err, ErrEmptyUpload) {
+		t.Fatalf("expected ErrEmptyUpload, got %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "empty.txt")
+	if err := ctx.SaveUploadedFile(&multipart.FileHeader{Filename: "empty.txt"}, dst); !errors.Is(err, ErrEmptyUpload) {
+		t.Fatalf("expected ErrEmptyUpload from save, got %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written, stat err: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package flow: per-request values.
+//
+// Context.Set and Context.Get share values between middleware and the
+// action handling the same request, eg. the current user:
+//
+//	func requireUser(app *flow.App) flow.Middleware {
+//		return func(next http.Handler) http.Handler {
+//			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//				flow.NewContext(app, w, r).Set("user", currentUser(r))
+//				next.ServeHTTP(w, r)
+//			})
+//		}
+//	}
+//
+// The App creates one store per request (see App.Handler) and every Context
+// built for that request uses it. Without an App the store belongs to the
+// Context alone.
+package flow
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// valuesCtxKey is the context key for the per-request value store.
+type valuesCtxKey struct{}
+
+// requestValues is the store behind Context.Set and Context.Get. It is
+// locked so goroutines started by a handler may use it too.
+type requestValues struct {
+	mu      sync.Mutex
+	m       map[string]interface{}
+	aborted bool
+}
+
+// withValues attaches an empty value store to each request. The App
+// installs it outside all user middleware.
+func withValues(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(valuesCtxKey{}).(*requestValues); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), valuesCtxKey{}, &requestValues{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// store returns the request's value store, creating a Context-local one
+// when the request has none.
+func (c *Context) store() *requestValues {
+	if c.values == nil {
+		if v, ok := c.R.Context().Value(valuesCtxKey{}).(*requestValues); ok {
+			c.values = v
+		} else {
+			c.values = &requestValues{}
+		}
+	}
+	return c.values
+}
+
+// Set stores v under key for the rest of the request.
+func (c *Context) Set(key string, v interface{}) {
+	s := c.store()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]interface{}{}
+	}
+	s.m[key] = v
+}
+
+// Get returns the value stored under key and whether it was set.
+func (c *Context) Get(key string) (interface{}, bool) {
+	s := c.store()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// GetString returns the string stored under key, or "" when it is missing
+// or not a string.
+func (c *Context) GetString(key string) string {
+	v, _ := c.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// GetInt returns the int stored under key, or 0 when it is missing or not
+// an int.
+func (c *Context) GetInt(key string) int {
+	v, _ := c.Get(key)
+	n, _ := v.(int)
+	return n
+}
@@ -0,0 +1,48 @@
+package flow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_ValuesFromMiddlewareToHandler(t *testing.T) {
+	app := New("values-test")
+	app.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := NewContext(app, w, r)
+			c.Set("user", "ada")
+			c.Set("role_id", 7)
+			next.ServeHTTP(w, r)
+		})
+	})
+	r := NewRouter(app)
+	r.Get("/me", func(c *Context) {
+		if _, ok := c.Get("missing"); ok {
+			t.Errorf("expected missing key to be unset")
+		}
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"user":    c.GetString("user"),
+			"role_id": c.GetInt("role_id"),
+			"bad_int": c.GetInt("user"),
+		})
+	})
+	app.SetRouter(r)
+
+	rec := httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/me", nil))
+	if want := `{"bad_int":0,"role_id":7,"user":"ada"}` + "\n"; rec.Body.String() != want {
+		t.Fatalf("expected %s, got %s", want, rec.Body.String())
+	}
+}
+
+func TestContext_ValuesWithoutApp(t *testing.T) {
+	c := NewContext(nil, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if v, ok := c.Get("k"); ok || v != nil {
+		t.Fatalf("expected an empty store, got %v", v)
+	}
+	c.Set("k", "v")
+	if c.GetString("k") != "v" || c.GetInt("k") != 0 {
+		t.Fatalf("unexpected values: %q, %d", c.GetString("k"), c.GetInt("k"))
+	}
+}
@@ -4,14 +4,32 @@
 // render templates according to conventions. It is intentionally minimal
 // for the prototype: templates are looked up by name relative to a root
 // directory and parsed on first use.
+//
+// Templates embedded with go:embed are served through SetFS, eg. for a
+// single-binary deploy:
+//
+//	//go:embed views
+//	var viewsFS embed.FS
+//
+//	sub, _ := fs.Sub(viewsFS, "views")
+//	app.Views.SetFS(sub)
 package flow
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"text/template/parse"
+
+	fsnotify "github.com/fsnotify/fsnotify"
 )
 
 // ViewManager holds template loading configuration and a simple cache.
@@ -25,105 +43,519 @@ type ViewManager struct {
 	// FuncMap contains template functions to register with parsed templates.
 	FuncMap template.FuncMap
 
+	// ContentBlock is the name of the template block executed on Render
+	// (layouts typically invoke it via {{ template "content" . }}). If the
+	// parsed set does not define it, the view file's base name is executed.
+	ContentBlock string
+
+	// PartialDirs lists additional directories (relative to TemplateDir)
+	// scanned recursively for partial templates. "partials" and "shared"
+	// are always scanned.
+	PartialDirs []string
+
+	// StrictDefines makes loading fail when the same template name is
+	// defined in more than one file. By default the last parsed wins.
+	StrictDefines bool
+
+	// Buffered executes templates into a buffer and writes the response only
+	// once execution succeeds, so a template error never leaves a partial
+	// page behind. NewViewManager enables it; turn it off for templates
+	// that flush or stream large output.
+	Buffered bool
+
+	// LeftDelim and RightDelim replace the "{{" and "}}" action delimiters
+	// when set, eg. "[[" and "]]" for views that also embed Vue or Angular
+	// templates. Empty values use the defaults.
+	LeftDelim  string
+	RightDelim string
+
 	// DevMode disables caching and forces reparsing on each Render call when true.
 	DevMode bool
-	mu      sync.RWMutex
-	cache   map[string]*template.Template
+	// fsys, when set via SetFS, replaces TemplateDir as the template root.
+	fsys  fs.FS
+	mu    sync.RWMutex
+	cache map[string]*template.Template
+	// deps records the files each cached template was parsed from so the
+	// watcher can invalidate only affected entries.
+	deps    map[string][]string
+	watcher *fsnotify.Watcher
 }
 
 // NewViewManager constructs a ViewManager which will look for templates in
 // templateDir (relative to the working directory).
 func NewViewManager(templateDir string) *ViewManager {
-	return &ViewManager{TemplateDir: templateDir, cache: make(map[string]*template.Template), FuncMap: template.FuncMap{}}
+	return &ViewManager{TemplateDir: templateDir, ContentBlock: "content", Buffered: true, cache: make(map[string]*template.Template), FuncMap: template.FuncMap{}}
 }
 
+// ErrViewsNotConfigured is returned when rendering through an App (or a
+// ViewManager) that has no views configured, eg. a JSON-only app.
+var ErrViewsNotConfigured = errors.New("render: views not configured")
+
 // Render loads (or retrieves from cache) the named template and executes it
 // with the provided data into the context's ResponseWriter. Template names
 // are file paths relative to TemplateDir without extension, e.g. "users/show".
 func (v *ViewManager) Render(name string, data interface{}, ctx *Context) error {
 	if v == nil {
-		return fmt.Errorf("view manager: nil")
+		return ErrViewsNotConfigured
+	}
+	return v.render(name, data, ctx)
+}
+
+// RenderFragment renders only the named view's content block, eg. for an
+// HTMX swap. Layouts are still parsed, so blocks they define (eg. "header")
+// resolve, but layout markup outside the content block is never written.
+// Since Render executes the same block, a handler can use either for both
+// full pages and partial swaps.
+func (v *ViewManager) RenderFragment(name string, data interface{}, ctx *Context) error {
+	if v == nil {
+		return ErrViewsNotConfigured
 	}
+	return v.render(name, data, ctx)
+}
+
+func (v *ViewManager) render(name string, data interface{}, ctx *Context) error {
 	tpl, err := v.loadTemplate(name)
 	if err != nil {
 		return err
 	}
-	// Prefer executing a "content" template (common pattern where views
+	execName := v.execName(tpl, name)
+	if !v.isBuffered() {
+		return ctx.streamTemplate(tpl, execName, data)
+	}
+	return ctx.RenderTemplate(tpl, execName, data)
+}
+
+// RenderToString renders the named view with its layout like Render, but
+// returns the output instead of writing a response, eg. for emails or
+// caching. It shares Render's template cache and needs no Context.
+func (v *ViewManager) RenderToString(name string, data interface{}) (string, error) {
+	if v == nil {
+		return "", ErrViewsNotConfigured
+	}
+	tpl, err := v.loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tpl.ExecuteTemplate(&buf, v.execName(tpl, name), data); err != nil {
+		return "", fmt.Errorf("render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// execName returns the template executed for view name in tpl.
+func (v *ViewManager) execName(tpl *template.Template, name string) string {
+	// Prefer executing the content block (common pattern where views
 	// define {{ define "content" }}...{{ end }} and layouts render that
-	// via {{ template "content" . }}). If no "content" template exists,
-	// fall back to executing the parsed file's base name (e.g. "show.html").
-	execName := "content"
+	// via {{ template "content" . }}). If no such template exists, fall
+	// back to executing the parsed file's base name (e.g. "show.html").
+	execName := v.ContentBlock
+	if execName == "" {
+		execName = "content"
+	}
 	if tpl.Lookup(execName) == nil {
 		execName = filepath.Base(name) + ".html"
 	}
-	return ctx.RenderTemplate(tpl, execName, data)
+	return execName
 }
 
+func (v *ViewManager) isBuffered() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.Buffered
+}
+
+// loadTemplate parses (or retrieves from cache) the named view together
+// with the layouts and partials.
 func (v *ViewManager) loadTemplate(name string) (*template.Template, error) {
-	// If not in dev mode, try cache first.
-	if !v.DevMode {
-		v.mu.RLock()
-		t, ok := v.cache[name]
-		v.mu.RUnlock()
-		if ok {
-			return t, nil
-		}
+	if t, ok := v.cached(name); ok {
+		return t, nil
 	}
 
 	// build list of candidate files: default layout (if set), layouts, partials, shared, then the view
-	var files []string
+	files := v.layoutFiles()
 
-	// if a DefaultLayout is specified, prefer it first
-	if v.DefaultLayout != "" {
-		defPath := filepath.Join(v.TemplateDir, v.DefaultLayout)
-		if _, err := os.Stat(defPath); err == nil {
-			files = append(files, defPath)
-		}
-	} else {
-		// collect layouts (prefer application/layout order)
-		layoutGlob := filepath.Join(v.TemplateDir, "layouts", "*.html")
-		if lays, _ := filepath.Glob(layoutGlob); len(lays) > 0 {
-			files = append(files, lays...)
-		}
+	// collect partials and shared helpers (walked recursively)
+	partials, err := v.partialFiles()
+	if err != nil {
+		return nil, err
 	}
+	files = append(files, partials...)
 
-	// collect partials
-	partialGlob := filepath.Join(v.TemplateDir, "partials", "*.html")
-	if parts, _ := filepath.Glob(partialGlob); len(parts) > 0 {
-		files = append(files, parts...)
+	// finally add the view file itself
+	viewPath := v.templatePath(name + ".html")
+	if err := v.statFile(viewPath); err != nil {
+		return nil, fmt.Errorf("view file not found: %s", viewPath)
 	}
+	files = append(files, templateFile{name: filepath.Base(viewPath), path: viewPath})
 
-	// collect shared helpers (optional)
-	sharedGlob := filepath.Join(v.TemplateDir, "shared", "*.html")
-	if sh, _ := filepath.Glob(sharedGlob); len(sh) > 0 {
-		files = append(files, sh...)
+	return v.parseAndCache(name, filepath.Base(viewPath), files)
+}
+
+// cached returns the template cached under key, unless in dev mode.
+func (v *ViewManager) cached(key string) (*template.Template, bool) {
+	if v.DevMode {
+		return nil, false
 	}
+	v.mu.RLock()
+	t, ok := v.cache[key]
+	v.mu.RUnlock()
+	return t, ok
+}
 
-	// finally add the view file itself
-	viewPath := filepath.Join(v.TemplateDir, name+".html")
-	if _, err := os.Stat(viewPath); err != nil {
-		return nil, fmt.Errorf("view file not found: %s", viewPath)
+// parseAndCache parses files into a set named root, registering the
+// FuncMap, and caches it under key unless in dev mode.
+func (v *ViewManager) parseAndCache(key, root string, files []templateFile) (*template.Template, error) {
+	if v.StrictDefines {
+		if err := checkDuplicateDefines(files, v.readFile, v.LeftDelim, v.RightDelim); err != nil {
+			return nil, err
+		}
 	}
-	files = append(files, viewPath)
 
 	// parse template set and register FuncMap if provided
-	tpl := template.New(filepath.Base(viewPath))
+	tpl := template.New(root).Delims(v.LeftDelim, v.RightDelim)
 	if v.FuncMap != nil {
 		tpl = tpl.Funcs(v.FuncMap)
 	}
-	parsed, err := tpl.ParseFiles(files...)
+	parsed, err := parseTemplateFiles(tpl, files, v.readFile)
 	if err != nil {
-		return nil, fmt.Errorf("parse templates %v: %w", files, err)
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.path)
+		}
+		return nil, fmt.Errorf("parse templates %v: %w", paths, err)
 	}
 
 	if !v.DevMode {
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, filepath.Clean(f.path))
+		}
 		v.mu.Lock()
-		v.cache[name] = parsed
+		// a ViewManager built as a struct literal has no cache yet
+		if v.cache == nil {
+			v.cache = make(map[string]*template.Template)
+		}
+		v.cache[key] = parsed
+		if v.deps == nil {
+			v.deps = make(map[string][]string)
+		}
+		v.deps[key] = paths
 		v.mu.Unlock()
 	}
 	return parsed, nil
 }
 
+// Precompile parses and caches every view under the template root (or the
+// FS set by SetFS) with its layout, so the first request to each view does
+// not pay the parse cost and a broken template fails at boot. Files under
+// layouts and the partial directories are not views and are skipped. The
+// first error is returned. In DevMode templates are parsed but not cached.
+func (v *ViewManager) Precompile() error {
+	if v == nil {
+		return ErrViewsNotConfigured
+	}
+	fsys := v.fsys
+	if fsys == nil {
+		fsys = os.DirFS(v.TemplateDir)
+	}
+	skip := map[string]bool{"layouts": true}
+	for _, d := range v.partialDirs() {
+		skip[filepath.ToSlash(d)] = true
+	}
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("precompile views: %w", err)
+		}
+		if d.IsDir() {
+			if skip[p] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(p) != ".html" || p == path.Clean(filepath.ToSlash(v.DefaultLayout)) {
+			return nil
+		}
+		_, err = v.loadTemplate(strings.TrimSuffix(p, ".html"))
+		return err
+	})
+}
+
+// RenderPartial renders a single partial, eg. "forms/input" for
+// partials/forms/input.html, without any layout or view: only the partial
+// directories (partials, shared and PartialDirs) are parsed, with the
+// FuncMap. The partial's own body is executed, so it can call helpers
+// defined in other partials. Useful for HTMX responses that swap one
+// component.
+func (v *ViewManager) RenderPartial(name string, data interface{}, ctx *Context) error {
+	if v == nil {
+		return ErrViewsNotConfigured
+	}
+	execName := path.Clean(filepath.ToSlash(name)) + ".html"
+	tpl, err := v.loadPartial(execName)
+	if err != nil {
+		return err
+	}
+	if !v.isBuffered() {
+		return ctx.streamTemplate(tpl, execName, data)
+	}
+	return ctx.RenderTemplate(tpl, execName, data)
+}
+
+// loadPartial parses (or retrieves from cache) the partial directories for
+// rendering the partial file named file, which is parsed last.
+func (v *ViewManager) loadPartial(file string) (*template.Template, error) {
+	key := file + "#partial"
+	if t, ok := v.cached(key); ok {
+		return t, nil
+	}
+	partials, err := v.partialFiles()
+	if err != nil {
+		return nil, err
+	}
+	var files []templateFile
+	var target *templateFile
+	for _, f := range partials {
+		if f.name == file {
+			f := f
+			target = &f
+			continue
+		}
+		files = append(files, f)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("partial not found: %s", file)
+	}
+	files = append(files, *target)
+	return v.parseAndCache(key, file, files)
+}
+
+// layoutFiles returns the layout templates parsed before partials and the
+// view: DefaultLayout when set, otherwise every layouts/*.html file.
+func (v *ViewManager) layoutFiles() []templateFile {
+	var files []templateFile
+	// if a DefaultLayout is specified, prefer it first
+	if v.DefaultLayout != "" {
+		defPath := v.templatePath(v.DefaultLayout)
+		if err := v.statFile(defPath); err == nil {
+			files = append(files, templateFile{name: filepath.Base(defPath), path: defPath})
+		}
+		return files
+	}
+	// collect layouts (prefer application/layout order)
+	lays, _ := v.globFiles(v.templatePath("layouts/*.html"))
+	for _, l := range lays {
+		files = append(files, templateFile{name: filepath.Base(l), path: l})
+	}
+	return files
+}
+
+// templatePath joins the slash-separated rel onto the template root: a path
+// within the FS set by SetFS, or otherwise a disk path under TemplateDir.
+func (v *ViewManager) templatePath(rel string) string {
+	if v.fsys != nil {
+		return path.Clean(filepath.ToSlash(rel))
+	}
+	return filepath.Join(v.TemplateDir, filepath.FromSlash(rel))
+}
+
+// statFile reports whether p (from templatePath) exists.
+func (v *ViewManager) statFile(p string) error {
+	if v.fsys != nil {
+		_, err := fs.Stat(v.fsys, p)
+		return err
+	}
+	_, err := os.Stat(p)
+	return err
+}
+
+// readFile reads p (from templatePath).
+func (v *ViewManager) readFile(p string) ([]byte, error) {
+	if v.fsys != nil {
+		return fs.ReadFile(v.fsys, p)
+	}
+	return os.ReadFile(p)
+}
+
+// globFiles returns the files matching pattern (from templatePath).
+func (v *ViewManager) globFiles(pattern string) ([]string, error) {
+	if v.fsys != nil {
+		return fs.Glob(v.fsys, pattern)
+	}
+	return filepath.Glob(pattern)
+}
+
+// templateFile pairs a template file path with the name it is parsed under.
+type templateFile struct {
+	name string
+	path string
+}
+
+// partialDirs returns the directories (relative to TemplateDir) scanned for
+// partial templates: the conventional "partials" and "shared" plus any
+// configured via PartialDirs.
+func (v *ViewManager) partialDirs() []string {
+	dirs := []string{"partials", "shared"}
+	for _, d := range v.PartialDirs {
+		d = filepath.Clean(d)
+		if d == "partials" || d == "shared" {
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// partialFiles collects the files of every partial directory. Partials are
+// named relative to their directory, so the same name in two directories
+// (eg. partials/row.html and shared/row.html) would shadow one another; that
+// is reported as an error naming both files.
+func (v *ViewManager) partialFiles() ([]templateFile, error) {
+	var out []templateFile
+	seen := map[string]string{}
+	for _, dir := range v.partialDirs() {
+		for _, f := range v.collectPartials(v.templatePath(dir)) {
+			if prev, ok := seen[f.name]; ok {
+				return nil, fmt.Errorf("view: partial %q found in both %s and %s", f.name, prev, f.path)
+			}
+			seen[f.name] = f.path
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// collectPartials walks root recursively and returns every .html file in
+// lexical order. Files are named by their slash-separated path relative to
+// root ("forms/input.html") so nested partials with the same base name do
+// not collide; top-level files keep their base name.
+func (v *ViewManager) collectPartials(root string) []templateFile {
+	var out []templateFile
+	if v.fsys != nil {
+		_ = fs.WalkDir(v.fsys, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(p) != ".html" {
+				return nil
+			}
+			out = append(out, templateFile{name: strings.TrimPrefix(p, root+"/"), path: p})
+			return nil
+		})
+		return out
+	}
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// missing directories are optional
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		out = append(out, templateFile{name: filepath.ToSlash(rel), path: p})
+		return nil
+	})
+	return out
+}
+
+// parseTemplateFiles parses files into tpl, mirroring template.ParseFS
+// but using each file's configured name rather than its base name.
+func parseTemplateFiles(tpl *template.Template, files []templateFile, read func(string) ([]byte, error)) (*template.Template, error) {
+	for _, f := range files {
+		b, err := read(f.path)
+		if err != nil {
+			return nil, err
+		}
+		var t *template.Template
+		if f.name == tpl.Name() {
+			t = tpl
+		} else {
+			t = tpl.New(f.name)
+		}
+		if _, err := t.Parse(string(b)); err != nil {
+			return nil, err
+		}
+	}
+	return tpl, nil
+}
+
+// checkDuplicateDefines reports an error naming both files when a template
+// name (a {{define}} block or a non-empty file body) is defined more than
+// once across files. Function calls are not resolved here, so the FuncMap is
+// not required. read loads each file; left and right are the action
+// delimiters ("" for defaults).
+func checkDuplicateDefines(files []templateFile, read func(string) ([]byte, error), left, right string) error {
+	seen := map[string]string{}
+	for _, f := range files {
+		b, err := read(f.path)
+		if err != nil {
+			return err
+		}
+		tree := parse.New(f.name)
+		tree.Mode = parse.SkipFuncCheck
+		trees := map[string]*parse.Tree{}
+		if _, err := tree.Parse(string(b), left, right, trees); err != nil {
+			return fmt.Errorf("parse template %s: %w", f.path, err)
+		}
+		names := make([]string, 0, len(trees))
+		for n, t := range trees {
+			if t.Root == nil || parse.IsEmptyTree(t.Root) {
+				continue
+			}
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			if prev, ok := seen[n]; ok {
+				return fmt.Errorf("view: template %q defined in both %s and %s", n, prev, f.path)
+			}
+			seen[n] = f.path
+		}
+	}
+	return nil
+}
+
+// SetStrictDefines toggles strict mode, in which duplicate template
+// definitions across files are reported as errors. Clears the cache.
+func (v *ViewManager) SetStrictDefines(strict bool) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.StrictDefines = strict
+	v.cache = make(map[string]*template.Template)
+	v.mu.Unlock()
+}
+
+// SetPartialDirs configures additional directories (relative to TemplateDir)
+// whose templates are loaded alongside "partials" and "shared". Changing the
+// directories clears the cache.
+func (v *ViewManager) SetPartialDirs(dirs ...string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.PartialDirs = append([]string(nil), dirs...)
+	v.cache = make(map[string]*template.Template)
+	v.mu.Unlock()
+}
+
+// SetFS loads templates from fsys instead of TemplateDir, eg. an embed.FS
+// (use fs.Sub to drop a leading "views" directory). Template, layout and
+// partial paths are then relative to the root of fsys; a nil fsys restores
+// reading from disk. Changing the filesystem clears the cache.
+func (v *ViewManager) SetFS(fsys fs.FS) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.fsys = fsys
+	v.cache = make(map[string]*template.Template)
+	v.deps = nil
+	v.mu.Unlock()
+}
+
 // SetDefaultLayout sets the default layout file (relative to TemplateDir).
 func (v *ViewManager) SetDefaultLayout(layout string) {
 	if v == nil {
@@ -136,6 +568,20 @@ func (v *ViewManager) SetDefaultLayout(layout string) {
 	v.mu.Unlock()
 }
 
+// SetContentBlock sets the name of the block executed on Render. An empty
+// name restores the default "content".
+func (v *ViewManager) SetContentBlock(name string) {
+	if v == nil {
+		return
+	}
+	if name == "" {
+		name = "content"
+	}
+	v.mu.Lock()
+	v.ContentBlock = name
+	v.mu.Unlock()
+}
+
 // SetFuncMap registers template functions to be available during parsing.
 // Changing the FuncMap clears the cache so new functions are available.
 func (v *ViewManager) SetFuncMap(m template.FuncMap) {
@@ -148,6 +594,29 @@ func (v *ViewManager) SetFuncMap(m template.FuncMap) {
 	v.mu.Unlock()
 }
 
+// Delims sets the template action delimiters used when parsing views, like
+// template.Delims; empty values restore "{{" and "}}". Changing the
+// delimiters clears the cache.
+func (v *ViewManager) Delims(left, right string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.LeftDelim, v.RightDelim = left, right
+	v.cache = make(map[string]*template.Template)
+	v.mu.Unlock()
+}
+
+// SetBuffered toggles buffered rendering (see Buffered).
+func (v *ViewManager) SetBuffered(buffered bool) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.Buffered = buffered
+	v.mu.Unlock()
+}
+
 // SetDevMode toggles development mode. When true templates are reparsed on
 // every Render call and caching is disabled.
 func (v *ViewManager) SetDevMode(dev bool) {
@@ -1,11 +1,15 @@
 package flow
 
 import (
+	"context"
+	"errors"
 	"html/template"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -220,3 +224,494 @@ func TestApp_WithViewsFuncMap(t *testing.T) {
 		t.Fatalf("unexpected output from app funcmap: %q", out)
 	}
 }
+
+func TestViewManager_CustomContentBlock(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), "{{define \"layout\"}}<main>{{template \"main\" .}}</main>{{end}}")
+	writeFile(t, filepath.Join(tmp, "pages", "home.html"), "{{define \"main\"}}HOME {{.}}{{end}}")
+
+	app := New("testapp", WithViewsContentBlock("main"))
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx := NewContext(app, rr, req)
+	if err := ctx.Render("pages/home", "x"); err != nil {
+		t.Fatalf("render with main block: %v", err)
+	}
+	if out := rr.Body.String(); out != "HOME x" {
+		t.Fatalf("unexpected output with custom content block: %q", out)
+	}
+}
+
+func TestViewManager_NestedPartials(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "partials", "header.html"), "TOP")
+	writeFile(t, filepath.Join(tmp, "partials", "forms", "header.html"), "NESTED")
+	writeFile(t, filepath.Join(tmp, "partials", "forms", "field.html"), "{{define \"field\"}}[{{.}}]{{end}}")
+	writeFile(t, filepath.Join(tmp, "components", "badge.html"), "{{define \"badge\"}}({{.}}){{end}}")
+	writeFile(t, filepath.Join(tmp, "forms", "edit.html"),
+		"{{define \"content\"}}{{template \"header.html\"}} {{template \"forms/header.html\"}} {{template \"field\" .}} {{template \"badge\" .}}{{end}}")
+
+	app := New("testapp", WithViewsPartialDirs("components"))
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx := NewContext(app, rr, req)
+	if err := ctx.Render("forms/edit", "x"); err != nil {
+		t.Fatalf("render with nested partials: %v", err)
+	}
+	if out := rr.Body.String(); out != "TOP NESTED [x] (x)" {
+		t.Fatalf("unexpected output with nested partials: %q", out)
+	}
+}
+
+func TestViewManager_PartialNameCollision(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "partials", "row.html"), "PARTIALS")
+	writeFile(t, filepath.Join(tmp, "shared", "row.html"), "SHARED")
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "{{define \"content\"}}{{template \"row.html\"}}{{end}}")
+
+	app := New("testapp")
+	app.Views.TemplateDir = tmp
+
+	ctx := NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	err := ctx.Render("items/index", nil)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(tmp, "partials", "row.html")) || !strings.Contains(err.Error(), filepath.Join(tmp, "shared", "row.html")) {
+		t.Fatalf("expected a collision error naming both files, got %v", err)
+	}
+	if err := ctx.RenderPartial("row", nil); err == nil || !strings.Contains(err.Error(), "found in both") {
+		t.Fatalf("expected RenderPartial to report the collision, got %v", err)
+	}
+}
+
+func TestViewManager_StrictDefinesDuplicate(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "layouts", "custom_layout.html"), "{{define \"shared\"}}FROM_CUSTOM{{end}}")
+	writeFile(t, filepath.Join(tmp, "layouts", "other.html"), "{{define \"shared\"}}FROM_OTHER{{end}}")
+	writeFile(t, filepath.Join(tmp, "items", "show.html"), "{{define \"content\"}}ITEM: {{template \"shared\" .}}{{end}}")
+
+	app := New("testapp", WithViewsStrictDefines())
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx := NewContext(app, rr, req)
+	err := ctx.Render("items/show", nil)
+	if err == nil {
+		t.Fatalf("expected duplicate define error in strict mode, got output %q", rr.Body.String())
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "\"shared\"") || !strings.Contains(msg, "custom_layout.html") || !strings.Contains(msg, "other.html") {
+		t.Fatalf("expected error naming the block and both files, got: %v", err)
+	}
+}
+
+func TestViewManager_WatchInvalidatesCache(t *testing.T) {
+	tmp := t.TempDir()
+
+	viewPath := filepath.Join(tmp, "users", "show.html")
+	writeFile(t, viewPath, "{{define \"content\"}}VERSION1: {{.}}{{end}}")
+
+	vm := NewViewManager(tmp)
+	if err := vm.StartWatching(); err != nil {
+		t.Fatalf("start watching: %v", err)
+	}
+	defer vm.StopWatching()
+	app := New("testapp")
+	app.Views = vm
+
+	render := func() string {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if err := NewContext(app, rr, req).Render("users/show", "X"); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return rr.Body.String()
+	}
+
+	if out := render(); out != "VERSION1: X" {
+		t.Fatalf("unexpected initial output: %q", out)
+	}
+
+	writeFile(t, viewPath, "{{define \"content\"}}VERSION2: {{.}}{{end}}")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		out := render()
+		if out == "VERSION2: X" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reloaded output after edit, got: %q", out)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestViewManager_RenderFragment(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), "<html>{{template \"content\" .}}</html>{{define \"header\"}}<h1>{{.}}</h1>{{end}}")
+	writeFile(t, filepath.Join(tmp, "partials", "row.html"), "{{define \"row\"}}<li>{{.}}</li>{{end}}")
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "{{define \"content\"}}{{template \"header\" .}}<ul>{{template \"row\" .}}</ul>{{end}}")
+
+	app := New("testapp")
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("GET", "/items", nil))
+	if err := ctx.RenderFragment("items/index", "a"); err != nil {
+		t.Fatalf("render fragment: %v", err)
+	}
+	// layout blocks resolve, but the layout markup is not written
+	if out := rr.Body.String(); out != "<h1>a</h1><ul><li>a</li></ul>" {
+		t.Fatalf("unexpected fragment output: %q", out)
+	}
+}
+
+func TestViewManager_HXRequestUsesLayoutBlocks(t *testing.T) {
+	tmp := t.TempDir()
+
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), "{{define \"nav\"}}NAV{{end}}")
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "{{define \"content\"}}{{template \"nav\"}} LIST{{end}}")
+
+	app := New("testapp")
+	app.Views.TemplateDir = tmp
+
+	for _, hx := range []string{"", "true"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/items", nil)
+		if hx != "" {
+			req.Header.Set("HX-Request", hx)
+		}
+		if err := NewContext(app, rr, req).Render("items/index", nil); err != nil {
+			t.Fatalf("render (HX-Request %q): %v", hx, err)
+		}
+		if out := rr.Body.String(); out != "NAV LIST" {
+			t.Fatalf("HX-Request %q: unexpected output: %q", hx, out)
+		}
+	}
+}
+
+func TestRender_NilViews(t *testing.T) {
+	app := New("testapp")
+	app.Views = nil
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("GET", "/", nil))
+	if err := ctx.Render("items/index", nil); !errors.Is(err, ErrViewsNotConfigured) {
+		t.Fatalf("expected ErrViewsNotConfigured from Render, got %v", err)
+	}
+	if err := ctx.RenderFragment("items/index", nil); !errors.Is(err, ErrViewsNotConfigured) {
+		t.Fatalf("expected ErrViewsNotConfigured from RenderFragment, got %v", err)
+	}
+	c := &Controller{App: app}
+	if err := c.Render(ctx, "items/index", nil); err == nil || !strings.Contains(err.Error(), "views not configured") {
+		t.Fatalf("expected descriptive controller error, got %v", err)
+	}
+	if rr.Body.Len() != 0 {
+		t.Fatalf("expected no output, got %q", rr.Body.String())
+	}
+}
+
+func TestViewManager_ZeroValueRenders(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "{{define \"content\"}}LIST{{end}}")
+
+	app := New("testapp")
+	app.Views = &ViewManager{TemplateDir: tmp}
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("GET", "/items", nil))
+	if err := ctx.Render("items/index", nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if out := rr.Body.String(); out != "LIST" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestViewManager_BufferedTemplateError(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "{{define \"content\"}}<ul><li>first</li>{{fail}}</ul>{{end}}")
+
+	funcs := template.FuncMap{"fail": func() (string, error) { return "", errors.New("boom") }}
+	app := New("testapp", WithViewsFuncMap(funcs))
+	app.Views.TemplateDir = tmp
+	c := &Controller{App: app}
+
+	rr := httptest.NewRecorder()
+	c.RenderOrError(NewContext(app, rr, httptest.NewRequest("GET", "/items", nil)), "items/index", nil)
+	if rr.Code != 500 {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "<li>first</li>") {
+		t.Fatalf("partial HTML leaked into response: %q", rr.Body.String())
+	}
+
+	// unbuffered views stream, so the partial output is already sent
+	app.Views.SetBuffered(false)
+	rr = httptest.NewRecorder()
+	c.RenderOrError(NewContext(app, rr, httptest.NewRequest("GET", "/items", nil)), "items/index", nil)
+	if rr.Code != 200 || !strings.Contains(rr.Body.String(), "<li>first</li>") {
+		t.Fatalf("expected streamed partial output, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestContext_RenderErrorStatus(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "posts", "new.html"), "{{define \"content\"}}<form>{{.}}</form>{{end}}")
+
+	app := New("testapp")
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("POST", "/posts", nil))
+	if err := ctx.RenderError(422, "posts/new", "title is required"); err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if rr.Code != 422 {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if out := rr.Body.String(); out != "<form>title is required</form>" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	// a later render on the same Context no longer carries the status
+	rr = httptest.NewRecorder()
+	ctx = NewContext(app, rr, httptest.NewRequest("GET", "/posts/new", nil))
+	if err := ctx.RenderError(422, "posts/missing", nil); err == nil {
+		t.Fatalf("expected error for missing template")
+	}
+	if err := ctx.Render("posts/new", "ok"); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rr.Code != 200 {
+		t.Fatalf("expected 200 after failed RenderError, got %d", rr.Code)
+	}
+}
+
+func TestViewManager_CustomDelims(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), "[[define \"layout\"]]<main>[[template \"content\" .]]</main>[[end]]")
+	writeFile(t, filepath.Join(tmp, "items", "index.html"), "[[define \"content\"]]<p>[[.Name]]</p><div id=\"app\">{{ message }}</div>[[end]]")
+
+	app := New("testapp", WithViewsDelims("[[", "]]"), WithViewsStrictDefines())
+	app.Views.TemplateDir = tmp
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("GET", "/items", nil))
+	if err := ctx.Render("items/index", map[string]string{"Name": "flow"}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if out := rr.Body.String(); !strings.Contains(out, "<p>flow</p>") || !strings.Contains(out, "{{ message }}") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestViewManager_SetFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/application.html": {Data: []byte(`{{define "layout"}}<main>{{template "content" .}}</main>{{end}}`)},
+		"partials/badge.html":      {Data: []byte(`{{define "badge"}}[{{.}}]{{end}}`)},
+		"pages/home.html":          {Data: []byte(`{{define "content"}}HOME {{template "badge" .}}{{end}}{{template "layout" .}}`)},
+	}
+	app := New("testapp", WithViewsFS(fsys), WithViewsDefaultLayout("layouts/application.html"))
+	// nothing should be read from disk
+	app.Views.TemplateDir = filepath.Join(t.TempDir(), "missing")
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(app, rr, httptest.NewRequest("GET", "/", nil))
+	if err := app.Views.Render("pages/home", "x", ctx); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	// the view is executed through its content block; the layout is parsed too
+	if got := rr.Body.String(); got != "HOME [x]" {
+		t.Fatalf("body = %q", got)
+	}
+	tpl, err := app.Views.loadTemplate("pages/home")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if tpl.Lookup("layout") == nil {
+		t.Fatalf("layout not parsed from fs")
+	}
+
+	if err := app.Views.Render("pages/missing", nil, NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))); err == nil {
+		t.Fatalf("expected error for view missing from fs")
+	}
+}
+
+func TestViewManager_SetFSLayoutGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/application.html": {Data: []byte(`<html>{{template "content" .}}</html>`)},
+		"home.html":                {Data: []byte(`{{define "content"}}hi {{.}}{{end}}`)},
+	}
+	vm := NewViewManager("views")
+	vm.SetFS(fsys)
+	vm.SetContentBlock("application.html")
+
+	rr := httptest.NewRecorder()
+	ctx := NewContext(New("testapp"), rr, httptest.NewRequest("GET", "/", nil))
+	if err := vm.Render("home", "bob", ctx); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got := rr.Body.String(); got != "<html>hi bob</html>" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestViewManager_SetFSDevModeDisk(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "home.html"), `{{define "content"}}v1{{end}}`)
+
+	vm := NewViewManager("unused")
+	vm.SetFS(os.DirFS(tmp))
+	vm.SetDevMode(true)
+	render := func() string {
+		rr := httptest.NewRecorder()
+		ctx := NewContext(New("testapp"), rr, httptest.NewRequest("GET", "/", nil))
+		if err := vm.Render("home", nil, ctx); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return rr.Body.String()
+	}
+	if got := render(); got != "v1" {
+		t.Fatalf("first render = %q", got)
+	}
+	writeFile(t, filepath.Join(tmp, "home.html"), `{{define "content"}}v2{{end}}`)
+	if got := render(); got != "v2" {
+		t.Fatalf("dev mode did not reparse: %q", got)
+	}
+}
+
+func TestViewManager_RenderPartial(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), `<html>LAYOUT {{template "content" .}}</html>`)
+	writeFile(t, filepath.Join(tmp, "partials", "forms", "input.html"), `<input value="{{up .}}">{{template "hint" .}}`)
+	writeFile(t, filepath.Join(tmp, "shared", "hint.html"), `{{define "hint"}}<small>{{.}}</small>{{end}}`)
+
+	app := New("testapp", WithViewsFuncMap(template.FuncMap{"up": strings.ToUpper}))
+	app.Views.TemplateDir = tmp
+
+	for i := 0; i < 2; i++ { // second pass renders from the cache
+		rr := httptest.NewRecorder()
+		ctx := NewContext(app, rr, httptest.NewRequest("GET", "/", nil))
+		if err := ctx.RenderPartial("forms/input", "bob"); err != nil {
+			t.Fatalf("render partial: %v", err)
+		}
+		out := rr.Body.String()
+		if strings.Contains(out, "LAYOUT") || strings.Contains(out, "<html>") {
+			t.Fatalf("partial rendered with layout: %q", out)
+		}
+		if out != `<input value="BOB"><small>bob</small>` {
+			t.Fatalf("unexpected output %q", out)
+		}
+	}
+
+	err := NewContext(app, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)).RenderPartial("missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "partial not found") {
+		t.Fatalf("expected partial not found, got %v", err)
+	}
+}
+
+func TestViewManager_RenderToString(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), `{{define "layout"}}<main>{{template "body" .}}</main>{{end}}`)
+	writeFile(t, filepath.Join(tmp, "mailers", "welcome.html"), `{{define "body"}}Hi {{.}}{{end}}{{define "content"}}{{template "layout" .}}{{end}}`)
+
+	vm := NewViewManager(tmp)
+	got, err := vm.RenderToString("mailers/welcome", "bob")
+	if err != nil {
+		t.Fatalf("render to string: %v", err)
+	}
+	if got != "<main>Hi bob</main>" {
+		t.Fatalf("unexpected output %q", got)
+	}
+
+	rr := httptest.NewRecorder()
+	if err := vm.Render("mailers/welcome", "bob", NewContext(New("testapp"), rr, httptest.NewRequest("GET", "/", nil))); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rr.Body.String() != got {
+		t.Fatalf("Render wrote %q, RenderToString returned %q", rr.Body.String(), got)
+	}
+	if _, ok := vm.cached("mailers/welcome"); !ok {
+		t.Fatalf("expected RenderToString to share the template cache")
+	}
+
+	if _, err := vm.RenderToString("mailers/missing", nil); err == nil {
+		t.Fatalf("expected error for a missing view")
+	}
+	var nilVM *ViewManager
+	if _, err := nilVM.RenderToString("x", nil); !errors.Is(err, ErrViewsNotConfigured) {
+		t.Fatalf("expected ErrViewsNotConfigured, got %v", err)
+	}
+}
+
+func TestViewManager_Precompile(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "layouts", "application.html"), `<html>{{template "content" .}}</html>`)
+	writeFile(t, filepath.Join(tmp, "partials", "nav.html"), `{{define "nav"}}nav{{end}}`)
+	writeFile(t, filepath.Join(tmp, "home.html"), `{{define "content"}}home{{end}}`)
+	writeFile(t, filepath.Join(tmp, "users", "show.html"), `{{define "content"}}{{template "nav"}} {{.}}{{end}}`)
+
+	vm := NewViewManager(tmp)
+	if err := vm.Precompile(); err != nil {
+		t.Fatalf("precompile: %v", err)
+	}
+	for _, name := range []string{"home", "users/show"} {
+		if _, ok := vm.cached(name); !ok {
+			t.Fatalf("expected %s to be cached", name)
+		}
+	}
+	for _, name := range []string{"layouts/application", "partials/nav"} {
+		if _, ok := vm.cached(name); ok {
+			t.Fatalf("%s is not a view and should not be cached", name)
+		}
+	}
+
+	writeFile(t, filepath.Join(tmp, "users", "edit.html"), `{{define "content"}}{{if}}{{end}}`)
+	vm = NewViewManager(tmp)
+	err := vm.Precompile()
+	if err == nil || !strings.Contains(err.Error(), "edit.html") {
+		t.Fatalf("expected a parse error naming edit.html, got %v", err)
+	}
+}
+
+func TestApp_WithViewsPrecompile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/application.html": {Data: []byte(`<html>{{template "content" .}}</html>`)},
+		"home.html":                {Data: []byte(`{{define "content"}}home{{end}}`)},
+	}
+	// the FS is configured after New; Start precompiles what is set by then
+	app := New("testapp", WithAddr("127.0.0.1:0"), WithLogger(NopLogger()), WithViewsPrecompile())
+	app.Views.SetFS(fsys)
+	if _, ok := app.Views.cached("home"); ok {
+		t.Fatalf("expected no precompiling before Start")
+	}
+	if err := app.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, ok := app.Views.cached("home"); !ok {
+		t.Fatalf("expected home to be precompiled")
+	}
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	fsys["broken.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{end`)}
+	broken := New("testapp", WithAddr("127.0.0.1:0"), WithLogger(NopLogger()), WithViewsFS(fsys), WithViewsPrecompile())
+	if err := broken.Start(); err == nil {
+		broken.Shutdown(context.Background())
+		t.Fatalf("expected Start to fail on a broken view")
+	}
+	if broken.ListenAddr() != nil {
+		t.Fatalf("expected Start not to listen after a broken view")
+	}
+}
@@ -0,0 +1,142 @@
+// Package flow: view hot-reload.
+//
+// This file adds an optional fsnotify-backed watcher to ViewManager. When
+// enabled, cached templates stay in use until one of the files they were
+// parsed from changes, at which point only the affected entries are dropped
+// and reparsed on the next render. This gives cached render performance with
+// automatic reloads, without the full reparse cost of DevMode.
+package flow
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	fsnotify "github.com/fsnotify/fsnotify"
+)
+
+// viewWatchDebounce is how long the watcher waits for further changes
+// before invalidating cache entries, so an editor's burst of writes on save
+// results in a single invalidation.
+const viewWatchDebounce = 100 * time.Millisecond
+
+// StartWatching watches TemplateDir (recursively) for changes and
+// invalidates cached templates that depend on a changed file. Creating,
+// removing or renaming files clears the whole cache because the set of
+// layouts and partials may have changed. Calling StartWatching while a
+// watcher is already running is a no-op.
+func (v *ViewManager) StartWatching() error {
+	if v == nil {
+		return fmt.Errorf("view manager: nil")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.watcher != nil {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("view watch: %w", err)
+	}
+	if err := addWatchDirs(w, v.TemplateDir); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("view watch %s: %w", v.TemplateDir, err)
+	}
+	v.watcher = w
+	go v.watchLoop(w)
+	return nil
+}
+
+// StopWatching stops the watcher started by StartWatching. It is safe to
+// call when no watcher is running.
+func (v *ViewManager) StopWatching() error {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	w := v.watcher
+	v.watcher = nil
+	v.mu.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.Close()
+}
+
+// addWatchDirs registers root and all of its subdirectories with w.
+func addWatchDirs(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.Add(p)
+	})
+}
+
+// watchLoop collects file events and applies them after a quiet period of
+// viewWatchDebounce. It returns when the watcher is closed.
+func (v *ViewManager) watchLoop(w *fsnotify.Watcher) {
+	debounce := time.NewTimer(viewWatchDebounce)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	defer debounce.Stop()
+
+	changed := map[string]struct{}{}
+	structural := false
+
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if ev.Op&fsnotify.Write == 0 {
+				structural = true
+				// watch newly created directories as well
+				if ev.Op&fsnotify.Create != 0 {
+					_ = addWatchDirs(w, ev.Name)
+				}
+			}
+			changed[filepath.Clean(ev.Name)] = struct{}{}
+			debounce.Reset(viewWatchDebounce)
+		case _, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+		case <-debounce.C:
+			v.invalidate(changed, structural)
+			changed = map[string]struct{}{}
+			structural = false
+		}
+	}
+}
+
+// invalidate drops cache entries parsed from any of the changed files, or
+// the whole cache when all is true.
+func (v *ViewManager) invalidate(changed map[string]struct{}, all bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if all {
+		v.cache = make(map[string]*template.Template)
+		v.deps = make(map[string][]string)
+		return
+	}
+	for name, files := range v.deps {
+		for _, f := range files {
+			if _, ok := changed[f]; ok {
+				delete(v.cache, name)
+				delete(v.deps, name)
+				break
+			}
+		}
+	}
+}
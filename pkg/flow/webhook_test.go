@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := `{"action":"opened"}`
+
+	var seen string
+	handler := VerifySignature("X-Hub-Signature-256", secret, sha256.New)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(nil, w, r)
+		b, ok := ctx.VerifiedBody()
+		if !ok {
+			t.Errorf("expected verified body in context")
+		}
+		var payload struct {
+			Action string `json:"action"`
+		}
+		if err := ctx.BindJSON(&payload); err != nil {
+			t.Errorf("bind json: %v", err)
+		}
+		seen = string(b) + "|" + payload.Action
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	good := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", good)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a valid signature, got %d", rr.Code)
+	}
+	if seen != body+"|opened" {
+		t.Fatalf("unexpected handler view %q", seen)
+	}
+
+	seen = ""
+	req = httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+strings.Repeat("00", sha256.Size))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad signature, got %d", rr.Code)
+	}
+	if seen != "" {
+		t.Fatalf("handler should not run for a bad signature")
+	}
+}